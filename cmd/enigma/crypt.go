@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/r3db34n1an/enigma"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"io"
+	"os"
+	"unicode"
+)
+
+func runCrypt(command string, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(stderr, "usage: enigma %v [options] [file ...]\n\noptions:\n", command)
+		flags.PrintDefaults()
+	}
+
+	var keys keyOptions
+	keys.register(flags)
+
+	preserveFormatting := flags.Bool("preserve-formatting", false, "keep spaces, punctuation and line breaks instead of grouping the output in fives")
+	preserveCase := flags.Bool("preserve-case", false, "keep lower case letters")
+	sanitize := flags.Bool("sanitize", false, "replace punctuation the historical way and drop everything else besides letters")
+	output := flags.String("o", "", "write to `file` instead of stdout")
+
+	parseError := parseFlags(flags, args)
+	if parseError != nil {
+		return parseError
+	}
+
+	setting, settingError := keys.setting()
+	if settingError != nil {
+		return settingError
+	}
+
+	machine, enigmaError := enigma.NewEnigma(*preserveFormatting, *preserveCase)
+	if enigmaError != nil {
+		return enigmaError
+	}
+
+	in, inError := openInput(flags.Args(), stdin)
+	if inError != nil {
+		return inError
+	}
+
+	defer func() { _ = in.Close() }()
+
+	if *sanitize {
+		data, readError := io.ReadAll(in)
+		if readError != nil {
+			return fmt.Errorf("failed to read input: %v", readError)
+		}
+
+		sanitized, sanitizeError := machine.Sanitize(string(bytes.ToUpper(data)))
+		if sanitizeError != nil {
+			return sanitizeError
+		}
+
+		in = io.NopCloser(bytes.NewReader([]byte(sanitized)))
+	}
+
+	// the grouping of the input gives way to the grouping in fives
+	if !*preserveFormatting {
+		in = struct {
+			io.Reader
+			io.Closer
+		}{transform.NewReader(in, runes.Remove(runes.In(unicode.White_Space))), in}
+	}
+
+	out := stdout
+	if len(*output) > 0 {
+		file, createError := os.Create(*output)
+		if createError != nil {
+			return fmt.Errorf("failed to create output: %v", createError)
+		}
+
+		defer func() { _ = file.Close() }()
+		out = file
+	}
+
+	buffered := bufio.NewWriter(out)
+	writer, writerError := machine.NewEncryptWriter(buffered, setting)
+	if writerError != nil {
+		return writerError
+	}
+
+	_, copyError := io.Copy(writer, in)
+	if copyError != nil {
+		return fmt.Errorf("failed to %v: %v", command, copyError)
+	}
+
+	closeError := writer.Close()
+	if closeError != nil {
+		return fmt.Errorf("failed to %v: %v", command, closeError)
+	}
+
+	// the grouped output has no line break of its own at the end
+	if !*preserveFormatting {
+		_, _ = buffered.WriteString("\n")
+	}
+
+	return buffered.Flush()
+}
+
+// openInput concatenates the files or returns stdin if there are none.
+func openInput(paths []string, stdin io.Reader) (io.ReadCloser, error) {
+	if len(paths) == 0 {
+		return io.NopCloser(stdin), nil
+	}
+
+	var files []io.Reader
+	var closers multiCloser
+	for _, path := range paths {
+		file, openError := os.Open(path) // #nosec:G304
+		if openError != nil {
+			_ = closers.Close()
+			return nil, fmt.Errorf("failed to open input: %v", openError)
+		}
+
+		files = append(files, file)
+		closers = append(closers, file)
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(files...), closers}, nil
+}
+
+type multiCloser []io.Closer
+
+func (what multiCloser) Close() error {
+	var firstError error
+	for _, closer := range what {
+		closeError := closer.Close()
+		if closeError != nil && firstError == nil {
+			firstError = closeError
+		}
+	}
+
+	return firstError
+}
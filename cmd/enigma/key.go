@@ -0,0 +1,196 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultKey is the simulator's setting when no key is given: Enigma I with rotors I, II and III at A and reflector B.
+const defaultKey = "{rotors: [{name: I}, {name: II}, {name: III}], reflector: B}"
+
+// keyOptions are the options selecting the key, shared by encrypt and decrypt.
+type keyOptions struct {
+	file      string     // YAML file with the setting
+	inline    string     // YAML setting on the command line
+	day       int        // day of the key sheet
+	group     string     // Kenngruppe of the key sheet day
+	date      string     // date looked up in the key sheets
+	network   string     // network of the key sheet, for dates covered by several sheets
+	sheets    sheetFiles // key sheet files besides the embedded one
+	positions string     // Grundstellung, overrides the key
+	plugBoard string     // Steckerverbindungen, overrides the key
+}
+
+func (what *keyOptions) register(flags *flag.FlagSet) {
+	flags.StringVar(&what.file, "key", "", "read the key from a YAML `file`")
+	flags.StringVar(&what.inline, "k", "", "inline YAML `key`, e.g. \""+defaultKey+"\"")
+	flags.IntVar(&what.day, "day", 0, "use the key sheet setting of the `day`")
+	flags.StringVar(&what.group, "group", "", "use the key sheet setting with the Kenngruppe `group`")
+	flags.StringVar(&what.date, "date", "", "use the key sheet setting of the `date`, e.g. 1944-10-31")
+	flags.StringVar(&what.network, "network", "", "look the date up in the key sheets of the `network` only")
+	flags.Var(&what.sheets, "sheet", "add a key sheet `file` for -date, in the tabular layout or YAML, repeatable")
+	flags.StringVar(&what.positions, "positions", "", "rotor start `positions` from left to right, e.g. ABC")
+	flags.StringVar(&what.plugBoard, "plugboard", "", "plug board `pairs`, e.g. \"AB CD EF\"")
+}
+
+// setting reads the selected key and applies the overrides.
+func (what *keyOptions) setting() (*settings.Setting, error) {
+	exported, keyError := what.key()
+	if keyError != nil {
+		return nil, keyError
+	}
+
+	if len(what.positions) > 0 {
+		positions := strings.ToUpper(what.positions)
+		if len(positions) != len(exported.Rotors) {
+			return nil, newUsageError("invalid positions %q, expected %d letters", what.positions, len(exported.Rotors))
+		}
+
+		for index := range exported.Rotors {
+			exported.Rotors[index].Position = positions[index : index+1]
+		}
+	}
+
+	if len(what.plugBoard) > 0 {
+		if exported.Uhr != nil {
+			exported.Uhr.Plugs = what.plugBoard
+		} else {
+			var plugBoard settings.PlugBoard
+			plugBoardError := plugBoard.Parse(what.plugBoard)
+			if plugBoardError != nil {
+				return nil, newUsageError("invalid plug board: %v", plugBoardError)
+			}
+
+			exported.PlugBoard = plugBoard.Export()
+		}
+	}
+
+	var setting settings.Setting
+	importError := setting.Import(exported)
+	if importError != nil {
+		return nil, fmt.Errorf("invalid key: %v", importError)
+	}
+
+	return &setting, nil
+}
+
+func (what *keyOptions) key() (settings.ExportSetting, error) {
+	var exported settings.ExportSetting
+
+	switch sources := what.sources(); {
+	case sources == 0:
+		return exported, newUsageError("no key, use -key, -k, -day, -group or -date")
+
+	case sources > 1:
+		return exported, newUsageError("more than one key, use only one of -key, -k, -day, -group and -date")
+	}
+
+	switch {
+	case len(what.file) > 0:
+		data, readError := os.ReadFile(what.file)
+		if readError != nil {
+			return exported, fmt.Errorf("failed to read key: %v", readError)
+		}
+
+		parseError := exported.Parse(string(data))
+		if parseError != nil {
+			return exported, parseError
+		}
+
+	case len(what.inline) > 0:
+		parseError := exported.Parse(what.inline)
+		if parseError != nil {
+			return exported, parseError
+		}
+
+	case len(what.group) > 0:
+		var setting settings.Setting
+		groupError := setting.Get(what.group)
+		if groupError != nil {
+			return exported, groupError
+		}
+
+		exported = setting.Export()
+
+	case len(what.date) > 0:
+		setting, dateError := what.dateSetting()
+		if dateError != nil {
+			return exported, dateError
+		}
+
+		exported = setting.Export()
+
+	default:
+		var setting settings.Setting
+		dayError := setting.GetDay(what.day)
+		if dayError != nil {
+			return exported, dayError
+		}
+
+		exported = setting.Export()
+	}
+
+	return exported, nil
+}
+
+// given reports whether any key was selected.
+func (what *keyOptions) given() bool {
+	return what.sources() > 0
+}
+
+func (what *keyOptions) sources() int {
+	sources := 0
+	for _, given := range []bool{len(what.file) > 0, len(what.inline) > 0, what.day != 0, len(what.group) > 0, len(what.date) > 0} {
+		if given {
+			sources++
+		}
+	}
+
+	return sources
+}
+
+// dateSetting looks the date up in the embedded key sheet and the added ones.
+func (what *keyOptions) dateSetting() (*settings.Setting, error) {
+	date, dateError := time.Parse(time.DateOnly, what.date)
+	if dateError != nil {
+		return nil, newUsageError("invalid date %q, expected YYYY-MM-DD", what.date)
+	}
+
+	embedded, sheetsError := settings.GetKeySheets()
+	if sheetsError != nil {
+		return nil, sheetsError
+	}
+
+	sheets := slices.Clone(embedded)
+	for _, path := range what.sheets {
+		loadError := sheets.LoadFile(path)
+		if loadError != nil {
+			return nil, loadError
+		}
+	}
+
+	if len(what.network) > 0 {
+		sheets = sheets.Network(what.network)
+	}
+
+	setting, _, settingError := sheets.ForDate(date)
+
+	return setting, settingError
+}
+
+// sheetFiles collects the files of a repeated flag.
+type sheetFiles []string
+
+func (what *sheetFiles) String() string {
+	return strings.Join(*what, ", ")
+}
+
+func (what *sheetFiles) Set(value string) error {
+	*what = append(*what, value)
+	return nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+func runGenKey(args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("genkey", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		_, _ = fmt.Fprint(stderr, "usage: enigma genkey [options]\n\noptions:\n")
+		flags.PrintDefaults()
+	}
+
+	machineName := flags.String("machine", settings.DefaultMachine, "`machine` model")
+	seed := flags.Uint64("seed", 0, "draw the same keys for the same `seed`, random if not given")
+	plugPairs := flags.Int("plugs", settings.DefaultPlugPairs, "number of plug board `pairs`")
+	historical := flags.Bool("historical", false, "follow the rules of the german key sheets")
+	uhr := flags.Bool("uhr", false, "plug the Enigma Uhr with 10 pairs at a random position")
+	count := flags.Int("count", 1, "number of keys, one per day")
+
+	parseError := parseFlags(flags, args)
+	if parseError != nil {
+		return parseError
+	}
+
+	if *count < 1 {
+		return newUsageError("invalid count %d", *count)
+	}
+
+	seeded := false
+	flags.Visit(func(seen *flag.Flag) {
+		if seen.Name == "seed" {
+			seeded = true
+		}
+	})
+
+	generator := settings.NewRandomGenerator()
+	if seeded {
+		generator = settings.NewGenerator(*seed)
+	}
+
+	generator.Machine = *machineName
+	generator.PlugPairs = *plugPairs
+	generator.Historical = *historical
+	generator.Uhr = *uhr
+
+	for index := range *count {
+		setting, generateError := generator.Next()
+		if generateError != nil {
+			return generateError
+		}
+
+		exported := setting.Export()
+		infoError := exported.Generate()
+		if infoError != nil {
+			return infoError
+		}
+
+		if index > 0 {
+			_, _ = fmt.Fprintln(stdout, "---")
+		}
+
+		_, writeError := fmt.Fprint(stdout, exported.Key)
+		if writeError != nil {
+			return writeError
+		}
+	}
+
+	return nil
+}
+
+func runKeySheet(args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("keysheet", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		_, _ = fmt.Fprint(stderr, "usage: enigma keysheet [options]\n\noptions:\n")
+		flags.PrintDefaults()
+	}
+
+	day := flags.Int("day", 0, "print only the `day`")
+	group := flags.String("group", "", "print only the day with the Kenngruppe `group`")
+	sheet := flags.String("sheet", "", "read a transcribed key sheet `file` in the tabular layout instead of the embedded one")
+	generate := flags.Bool("generate", false, "draw a new key sheet following the german rules")
+	days := flags.Int("days", 31, "number of `days` of a generated key sheet")
+	seed := flags.Uint64("seed", 0, "draw the same key sheet for the same `seed`, random if not given")
+	machineName := flags.String("machine", settings.DefaultMachine, "`machine` model of a generated key sheet")
+	uhr := flags.Bool("uhr", false, "plug the Enigma Uhr in a generated key sheet")
+	format := flags.String("format", "text", "output `format`: text, csv, html or yaml")
+	title := flags.String("title", "", "`title` printed above the days")
+
+	parseError := parseFlags(flags, args)
+	if parseError != nil {
+		return parseError
+	}
+
+	if !slices.Contains([]string{"text", "csv", "html", "yaml"}, *format) {
+		return newUsageError("invalid format %q", *format)
+	}
+
+	var allSettings settings.Settings
+	if *generate {
+		if len(*sheet) > 0 {
+			return newUsageError("-generate and -sheet exclude each other")
+		}
+
+		seeded := false
+		flags.Visit(func(seen *flag.Flag) {
+			if seen.Name == "seed" {
+				seeded = true
+			}
+		})
+
+		generator := settings.NewRandomGenerator()
+		if seeded {
+			generator = settings.NewGenerator(*seed)
+		}
+
+		generator.Machine = *machineName
+		generator.Historical = true
+		generator.Uhr = *uhr
+		if *machineName == settings.DefaultMachine {
+			// the text layout has no reflector column, the army sheets assume UKW B
+			generator.Reflectors = []string{settings.SheetReflector}
+		}
+
+		month, generateError := generator.Month(*days)
+		if generateError != nil {
+			return generateError
+		}
+
+		allSettings = month
+	} else {
+		loaded, settingsError := loadSheet(*sheet)
+		if settingsError != nil {
+			return settingsError
+		}
+
+		allSettings = loaded
+	}
+
+	var selected settings.Settings
+	for index := range allSettings {
+		if *day != 0 && allSettings[index].Day != *day {
+			continue
+		}
+
+		if len(*group) > 0 && !slices.ContainsFunc(allSettings[index].IDGroups, func(idGroup string) bool {
+			return strings.EqualFold(idGroup, *group)
+		}) {
+			continue
+		}
+
+		selected = append(selected, allSettings[index])
+	}
+
+	if len(selected) == 0 {
+		return fmt.Errorf("no setting for day %d and Kenngruppe %q", *day, *group)
+	}
+
+	switch *format {
+	case "csv":
+		return selected.CSV(stdout)
+
+	case "html":
+		return selected.HTML(stdout, *title)
+
+	case "yaml":
+		data, yamlError := selected.YAML()
+		if yamlError != nil {
+			return yamlError
+		}
+
+		_, writeError := stdout.Write(data)
+		return writeError
+	}
+
+	// without a title only the days, like the lines of a cut off sheet
+	if len(*title) > 0 {
+		_, writeError := fmt.Fprint(stdout, selected.Sheet(*title))
+		return writeError
+	}
+
+	for index := range selected {
+		_, writeError := fmt.Fprintln(stdout, selected[index].SheetLine())
+		if writeError != nil {
+			return writeError
+		}
+	}
+
+	return nil
+}
+
+// loadSheet parses a key sheet file, the embedded sheet if path is empty.
+func loadSheet(path string) (settings.Settings, error) {
+	if len(path) == 0 {
+		return settings.GetSettings()
+	}
+
+	data, readError := os.ReadFile(path) // #nosec:G304
+	if readError != nil {
+		return nil, fmt.Errorf("failed to read key sheet: %v", readError)
+	}
+
+	var sheet settings.Settings
+	parseError := sheet.ParseSheet(data)
+	if parseError != nil {
+		return nil, parseError
+	}
+
+	return sheet, nil
+}
@@ -0,0 +1,121 @@
+// Command enigma encrypts and decrypts text with a simulated Enigma machine, generates keys and prints the key sheet.
+//
+// Usage:
+//
+//	enigma encrypt [options] [file ...]
+//	enigma decrypt [options] [file ...]
+//	enigma genkey [options]
+//	enigma keysheet [options]
+//	enigma tui [options]
+//
+// The text is read from the files or from stdin. The key is a YAML setting as printed by genkey, given as a file with
+// -key, inline with -k, as a day of the key sheet with -day, as the day with the Kenngruppe given with -group or by
+// calendar date with -date.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `usage: enigma <command> [options]
+
+commands:
+  encrypt   encrypt text from files or stdin
+  decrypt   decrypt text from files or stdin
+  genkey    print a random key
+  keysheet  print or generate a key sheet
+  tui       simulate the machine in the terminal, one key at a time
+
+run "enigma <command> -h" for the options of a command
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the exit code, 2 for usage errors.
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		_, _ = fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var commandError error
+	switch args[0] {
+	case "encrypt", "decrypt":
+		// the machine is its own inverse, both run the same way
+		commandError = runCrypt(args[0], args[1:], stdin, stdout, stderr)
+
+	case "genkey":
+		commandError = runGenKey(args[1:], stdout, stderr)
+
+	case "keysheet":
+		commandError = runKeySheet(args[1:], stdout, stderr)
+
+	case "tui":
+		commandError = runTUI(args[1:], stdin, stdout, stderr)
+
+	case "help", "-h", "-help", "--help":
+		_, _ = fmt.Fprint(stdout, usage)
+		return 0
+
+	default:
+		_, _ = fmt.Fprintf(stderr, "unknown command %q\n\n%v", args[0], usage)
+		return 2
+	}
+
+	if errors.Is(commandError, flag.ErrHelp) {
+		return 0
+	}
+
+	switch castError := commandError.(type) {
+	case nil:
+		return 0
+
+	case usageError:
+		// the flag package already reported its own errors
+		if len(castError.message) > 0 {
+			_, _ = fmt.Fprintf(stderr, "enigma %v: %v\n", args[0], commandError)
+		}
+
+		return 2
+
+	default:
+		_, _ = fmt.Fprintf(stderr, "enigma %v: %v\n", args[0], commandError)
+		return 1
+	}
+}
+
+// usageError marks errors in the command line, as opposed to errors while running the command.
+type usageError struct {
+	message string
+}
+
+func (what usageError) Error() string {
+	return what.message
+}
+
+func newUsageError(format string, args ...any) error {
+	return usageError{
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+// parseFlags parses the options of a command, the flag set reports errors and the help text itself.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	parseError := flags.Parse(args)
+	switch {
+	case parseError == nil:
+		return nil
+
+	case errors.Is(parseError, flag.ErrHelp):
+		return parseError
+
+	default:
+		return usageError{}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommandLine(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	// encrypt and decrypt with the key sheet
+	code := run([]string{"encrypt", "-day", "31", "-positions", "abc"}, strings.NewReader("HELLO WORLD\n"), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	cipherText := stdout.String()
+	assert.Regexp(t, `^[A-Z]{5} [A-Z]{5}\n$`, cipherText)
+
+	stdout.Reset()
+	code = run([]string{"decrypt", "-day", "31", "-positions", "ABC"}, strings.NewReader(cipherText), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "HELLO WORLD\n", stdout.String())
+
+	// the day is also found by its Kenngruppe
+	stdout.Reset()
+	code = run([]string{"decrypt", "-group", "JKM", "-positions", "ABC"}, strings.NewReader(cipherText), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "HELLO WORLD\n", stdout.String())
+
+	stdout.Reset()
+	code = run([]string{"keysheet", "-group", "ino"}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.True(t, strings.HasPrefix(stdout.String(), "St 30."), stdout.String())
+
+	// inline key with plug board override, sanitized and preserved input
+	key := "{rotors: [{name: I}, {name: II}, {name: III}], reflector: B}"
+	stdout.Reset()
+	code = run([]string{"encrypt", "-k", key, "-plugboard", "AB CD", "-sanitize"}, strings.NewReader("Hello, world."), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Len(t, strings.ReplaceAll(strings.TrimSpace(stdout.String()), " ", ""), len("HELLOYWORLDX"))
+
+	stdout.Reset()
+	code = run([]string{"encrypt", "-k", key, "-preserve-formatting", "-preserve-case"}, strings.NewReader("Hello, world."), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Regexp(t, `^[A-Z][a-z]{4}, [a-z]{5}\.$`, stdout.String())
+
+	// generated key written to a file, text read from a file
+	directory := t.TempDir()
+	stdout.Reset()
+	code = run([]string{"genkey", "-seed", "7", "-historical"}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	generated := stdout.String()
+
+	stdout.Reset()
+	code = run([]string{"genkey", "-seed", "7", "-historical"}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, generated, stdout.String())
+
+	stdout.Reset()
+	code = run([]string{"genkey", "-seed", "7", "-uhr"}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Regexp(t, `(?m)^uhr:\n    position: \d+\n    plugs: ([A-Z]{2} ){9}[A-Z]{2}\n`, stdout.String())
+
+	keyFile := filepath.Join(directory, "key.yaml")
+	plainFile := filepath.Join(directory, "plain.txt")
+	cipherFile := filepath.Join(directory, "cipher.txt")
+	assert.NoError(t, os.WriteFile(keyFile, []byte(generated), 0o600))
+	assert.NoError(t, os.WriteFile(plainFile, []byte("ANGRIFF IM MORGENGRAUEN"), 0o600))
+
+	code = run([]string{"encrypt", "-key", keyFile, "-o", cipherFile, plainFile}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+
+	stdout.Reset()
+	code = run([]string{"decrypt", "-key", keyFile, cipherFile}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "ANGRI FFIMM ORGEN GRAUE N\n", stdout.String())
+
+	// key sheet
+	stdout.Reset()
+	code = run([]string{"keysheet", "-day", "31"}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "St 31.  IV V I      21 15 16  KL IT FQ HY XC NP VZ JB SE OG  jkm ogi ncj glp\n", stdout.String())
+
+	sheetFile := filepath.Join(directory, "sheet.txt")
+	assert.NoError(t, os.WriteFile(sheetFile, []byte("St  7. I II III 01 02 03 AB CD abc def ghi jkl\n"), 0o600))
+
+	stdout.Reset()
+	code = run([]string{"keysheet", "-sheet", sheetFile}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "St  7.  I II III    01 02 03  AB CD                          abc def ghi jkl\n", stdout.String())
+
+	// the key sheet day by calendar date
+	stdout.Reset()
+	code = run([]string{"encrypt", "-day", "31"}, strings.NewReader("HELLO"), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	byDay := stdout.String()
+
+	stdout.Reset()
+	code = run([]string{"encrypt", "-date", "1944-10-31", "-network", "Armee-Stabs-Maschinenschlüssel"}, strings.NewReader("HELLO"), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, byDay, stdout.String())
+
+	stderr.Reset()
+	assert.Equal(t, 1, run([]string{"encrypt", "-date", "1944-11-01"}, strings.NewReader("HELLO"), &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "no key sheet")
+
+	// a generated key sheet, the same for the same seed
+	stdout.Reset()
+	code = run([]string{"keysheet", "-generate", "-seed", "28", "-days", "3", "-title", "Nr. 28"}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	generatedSheet := stdout.String()
+	assert.True(t, strings.HasPrefix(generatedSheet, "Nr. 28\n\nDatum "), generatedSheet)
+	assert.Contains(t, generatedSheet, "\nSt  3.  ")
+
+	stdout.Reset()
+	code = run([]string{"keysheet", "-generate", "-seed", "28", "-days", "3", "-title", "Nr. 28"}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, generatedSheet, stdout.String())
+
+	stdout.Reset()
+	code = run([]string{"keysheet", "-generate", "-uhr", "-seed", "28", "-days", "3"}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Regexp(t, `^St  3\.  .* Uhr \d{2}  `, stdout.String())
+
+	for _, format := range []string{"csv", "html", "yaml"} {
+		stdout.Reset()
+		code = run([]string{"keysheet", "-day", "30", "-format", format}, nil, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Contains(t, stdout.String(), "udl", format)
+	}
+
+	// usage errors
+	for _, args := range [][]string{
+		nil,
+		{"unknown"},
+		{"encrypt"},
+		{"encrypt", "-day", "31", "-k", key},
+		{"encrypt", "-day", "31", "-positions", "AB"},
+		{"encrypt", "-day", "31", "-group", "jkm"},
+		{"encrypt", "-undefined"},
+		{"keysheet", "-format", "pdf"},
+		{"encrypt", "-date", "31.10.1944"},
+		{"encrypt", "-day", "31", "-date", "1944-10-31"},
+		{"keysheet", "-generate", "-sheet", "sheet.txt"},
+	} {
+		stderr.Reset()
+		assert.Equal(t, 2, run(args, strings.NewReader(""), &stdout, &stderr), args)
+		assert.NotEmpty(t, stderr.String(), args)
+	}
+
+	// invalid input
+	stderr.Reset()
+	assert.Equal(t, 1, run([]string{"encrypt", "-day", "31"}, strings.NewReader("HELLO, WORLD"), &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "invalid character")
+}
+
+func TestSimulator(t *testing.T) {
+	keys := keyOptions{day: 31, positions: "ABC"}
+	setting, settingError := keys.setting()
+	assert.NoError(t, settingError)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"encrypt", "-day", "31", "-positions", "ABC"}, strings.NewReader("HELLOWORLD"), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+
+	// typing gives the same letters as encrypting, backspace turns the rotors back
+	simulator, simulatorError := newSimulator(setting)
+	assert.NoError(t, simulatorError)
+	var screen bytes.Buffer
+	assert.NoError(t, simulator.interact(strings.NewReader("hello world"), &screen))
+	assert.Equal(t, "HELLOWORLD", string(simulator.input))
+	assert.Equal(t, strings.TrimSpace(stdout.String()), groupLetters(simulator.output))
+	assert.Contains(t, screen.String(), fmt.Sprintf("(%c)", simulator.output[len(simulator.output)-1]))
+	assert.Contains(t, screen.String(), "[ M ]")
+
+	for range 5 {
+		simulator.backspace()
+	}
+
+	assert.Equal(t, "HELLO", string(simulator.input))
+	assert.Equal(t, "ABH", simulator.session.Positions())
+
+	simulator.handle([]byte("WORLD"))
+	assert.Equal(t, strings.TrimSpace(stdout.String()), groupLetters(simulator.output))
+
+	// hand turning and ring settings apply to the selected rotor
+	simulator.reset()
+	simulator.handle([]byte("\x1b[A\x1b[D\x1b[B\t+-+"))
+	assert.Equal(t, "AAD", simulator.session.Positions())
+	assert.Equal(t, "UOQ", simulator.session.RingSettings())
+
+	simulator.backspace()
+	assert.Equal(t, "nothing to take back", simulator.status)
+
+	// after turning by hand the rotors step back from the new positions
+	simulator.handle([]byte("AB\x1b[A\x7f"))
+	assert.Equal(t, "A", string(simulator.input))
+	assert.Equal(t, "AAF", simulator.session.Positions())
+
+	// ctrl-c quits before the remaining keys
+	simulator.reset()
+	assert.True(t, simulator.handle([]byte("A\x03B")))
+	assert.Equal(t, "A", string(simulator.input))
+
+	// the plugs of a key sheet day and of a yaml key with a plug board map
+	screen.Reset()
+	assert.NoError(t, simulator.render(&screen))
+	assert.Contains(t, screen.String(), "Plugs      BJ CX ES FQ GO HY IT KL NP VZ\r\n")
+
+	mapKeys := keyOptions{inline: "rotors: [{name: I}, {name: II}, {name: III}]\nreflector: B\nplug_board: {A: Z, B: Y}\n"}
+	mapSetting, mapSettingError := mapKeys.setting()
+	assert.NoError(t, mapSettingError)
+
+	simulator, simulatorError = newSimulator(mapSetting)
+	assert.NoError(t, simulatorError)
+	screen.Reset()
+	assert.NoError(t, simulator.render(&screen))
+	assert.Contains(t, screen.String(), "Plugs      AZ BY\r\n")
+
+	// the simulator needs a terminal
+	assert.Equal(t, 1, run([]string{"tui"}, strings.NewReader(""), &stdout, &stderr))
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"github.com/r3db34n1an/enigma/pkg/enigma"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"strings"
+)
+
+// lampRows is the layout of the lamp board, the same as the keyboard.
+var lampRows = []string{"QWERTZUIO", "ASDFGHJK", "PYXCVBNML"}
+
+const (
+	keyBackspace = 0x7f
+	keyDelete    = 0x08
+	keyCtrlC     = 0x03
+	keyCtrlD     = 0x04
+	keyCtrlR     = 0x12
+	keyTab       = 0x09
+	keyEscape    = 0x1b
+)
+
+const tuiHelp = "letters type, backspace takes back, left/right or tab select a rotor, up/down turn it, +/- change its " +
+	"ring setting, ctrl-r resets, ctrl-c quits"
+
+func runTUI(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("tui", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		_, _ = fmt.Fprint(stderr, "usage: enigma tui [options]\n\noptions:\n")
+		flags.PrintDefaults()
+	}
+
+	var keys keyOptions
+	keys.register(flags)
+
+	parseError := parseFlags(flags, args)
+	if parseError != nil {
+		return parseError
+	}
+
+	if !keys.given() {
+		keys.inline = defaultKey
+	}
+
+	setting, settingError := keys.setting()
+	if settingError != nil {
+		return settingError
+	}
+
+	file, isFile := stdin.(*os.File)
+	if !isFile || !term.IsTerminal(int(file.Fd())) {
+		return fmt.Errorf("the simulator needs a terminal")
+	}
+
+	state, rawError := term.MakeRaw(int(file.Fd()))
+	if rawError != nil {
+		return fmt.Errorf("failed to switch the terminal to raw mode: %v", rawError)
+	}
+
+	defer func() { _ = term.Restore(int(file.Fd()), state) }()
+
+	simulator, simulatorError := newSimulator(setting)
+	if simulatorError != nil {
+		return simulatorError
+	}
+
+	return simulator.interact(stdin, stdout)
+}
+
+// simulator is the live view of a machine, typing one key at a time.
+type simulator struct {
+	session  *enigma.Session
+	input    []byte
+	output   []byte
+	lamp     int // lit lamp, -1 if none
+	selected int // rotor turned by hand
+	status   string
+}
+
+func newSimulator(setting *settings.Setting) (*simulator, error) {
+	engine, engineError := enigma.NewEnigma(false, false)
+	if engineError != nil {
+		return nil, engineError
+	}
+
+	session, sessionError := engine.NewSession(setting)
+	if sessionError != nil {
+		return nil, sessionError
+	}
+
+	simulator := &simulator{
+		session: session,
+	}
+
+	simulator.reset()
+
+	return simulator, nil
+}
+
+func (what *simulator) reset() {
+	what.session.Reset()
+	what.input = nil
+	what.output = nil
+	what.lamp = -1
+	what.selected = len(what.session.Positions()) - 1
+	what.status = ""
+}
+
+// press steps the rotors and lights the lamp of the key, like pressing it on the machine.
+func (what *simulator) press(key byte) {
+	lamp, pressError := what.session.Press(key)
+	if pressError != nil {
+		what.status = pressError.Error()
+		return
+	}
+
+	what.lamp = int(lamp - 'A')
+	what.input = append(what.input, key&^('a'-'A'))
+	what.output = append(what.output, lamp)
+	what.status = ""
+}
+
+// backspace takes back the last letter and steps the rotors back.
+func (what *simulator) backspace() {
+	if len(what.input) == 0 {
+		what.status = "nothing to take back"
+		return
+	}
+
+	stepError := what.session.StepBack()
+	if stepError != nil {
+		what.status = stepError.Error()
+		return
+	}
+
+	what.input = what.input[:len(what.input)-1]
+	what.output = what.output[:len(what.output)-1]
+	what.lamp = -1
+	what.status = ""
+}
+
+func (what *simulator) selectRotor(delta int) {
+	count := len(what.session.Positions())
+	what.selected = ((what.selected+delta)%count + count) % count
+}
+
+// turn moves the selected rotor by hand.
+func (what *simulator) turn(delta int) {
+	positions := []byte(what.session.Positions())
+	positions[what.selected] = defs.UpperCase[wrapLetter(int(positions[what.selected]-'A')+delta)]
+	_ = what.session.SetPositions(string(positions))
+
+	what.lamp = -1
+	what.status = fmt.Sprintf("rotor %v turned to %c", what.session.Setting().Rotors[what.selected].Name,
+		positions[what.selected])
+}
+
+// ring changes the ring setting of the selected rotor, the window letter stays the same.
+func (what *simulator) ring(delta int) {
+	ringSettings := []byte(what.session.RingSettings())
+	ringSettings[what.selected] = defs.UpperCase[wrapLetter(int(ringSettings[what.selected]-'A')+delta)]
+	_ = what.session.SetRingSettings(string(ringSettings))
+
+	what.lamp = -1
+	what.status = fmt.Sprintf("rotor %v ring setting %02d", what.session.Setting().Rotors[what.selected].Name,
+		ringSettings[what.selected]-'A'+1)
+}
+
+// interact reads keys until the input ends or the user quits, redrawing the machine after each one.
+func (what *simulator) interact(reader io.Reader, writer io.Writer) error {
+	buffer := make([]byte, 64)
+	for {
+		renderError := what.render(writer)
+		if renderError != nil {
+			return renderError
+		}
+
+		count, readError := reader.Read(buffer)
+		if count > 0 && what.handle(buffer[:count]) {
+			return what.render(writer)
+		}
+
+		if readError == io.EOF {
+			return nil
+		}
+
+		if readError != nil {
+			return fmt.Errorf("failed to read key: %v", readError)
+		}
+	}
+}
+
+// handle processes the keys of one read and reports whether to quit.
+func (what *simulator) handle(keys []byte) bool {
+	for index := 0; index < len(keys); index++ {
+		key := keys[index]
+
+		// cursor keys arrive as escape sequences, a lone escape quits
+		if key == keyEscape {
+			if index+2 >= len(keys) || keys[index+1] != '[' {
+				return true
+			}
+
+			switch keys[index+2] {
+			case 'A':
+				what.turn(1)
+
+			case 'B':
+				what.turn(-1)
+
+			case 'C':
+				what.selectRotor(1)
+
+			case 'D':
+				what.selectRotor(-1)
+			}
+
+			index += 2
+			continue
+		}
+
+		switch {
+		case key == keyCtrlC || key == keyCtrlD:
+			return true
+
+		case key == keyCtrlR:
+			what.reset()
+
+		case key == keyBackspace || key == keyDelete:
+			what.backspace()
+
+		case key == keyTab:
+			what.selectRotor(1)
+
+		case key == '+':
+			what.ring(1)
+
+		case key == '-':
+			what.ring(-1)
+
+		case letterIndex(key) >= 0:
+			what.press(key)
+		}
+	}
+
+	return false
+}
+
+// render draws the whole screen, lines end in CR LF as the terminal is in raw mode.
+func (what *simulator) render(writer io.Writer) error {
+	var builder strings.Builder
+	line := func(format string, args ...any) {
+		builder.WriteString(fmt.Sprintf(format, args...))
+		builder.WriteString("\r\n")
+	}
+
+	builder.WriteString("\x1b[H\x1b[2J")
+
+	machine := what.session.Setting()
+	machineName := settings.DefaultMachine
+	if machine.Machine != nil {
+		machineName = machine.Machine.Name
+	}
+
+	line("Enigma %v", machineName)
+	line("")
+
+	var names, windows, rings, marker strings.Builder
+	for index, rotor := range machine.Rotors {
+		width := max(len(rotor.Name), 5) + 2
+		names.WriteString(fmt.Sprintf("%-*v", width, rotor.Name))
+		windows.WriteString(fmt.Sprintf("%-*v", width, fmt.Sprintf("[ %c ]", defs.UpperCase[rotor.Position])))
+		rings.WriteString(fmt.Sprintf("%-*v", width, fmt.Sprintf("  %02d", rotor.RingSetting+1)))
+		if index == what.selected {
+			marker.WriteString(fmt.Sprintf("%-*v", width, "  ^"))
+		} else {
+			marker.WriteString(strings.Repeat(" ", width))
+		}
+	}
+
+	reflector := machine.Reflector.Name
+	if machine.Reflector.Settable {
+		reflector = fmt.Sprintf("%v %c", reflector, defs.UpperCase[machine.Reflector.Position])
+	}
+
+	line("Reflector  %v", reflector)
+	line("Rotors     %v", strings.TrimRight(names.String(), " "))
+	line("Window     %v", strings.TrimRight(windows.String(), " "))
+	line("Rings      %v", strings.TrimRight(rings.String(), " "))
+	line("           %v", strings.TrimRight(marker.String(), " "))
+	line("")
+
+	for offset, row := range lampRows {
+		var lamps strings.Builder
+		lamps.WriteString(strings.Repeat(" ", offset))
+		for index := range row {
+			letter := int(row[index] - 'A')
+			if letter == what.lamp {
+				lamps.WriteString(fmt.Sprintf("\x1b[7m(%c)\x1b[0m ", row[index]))
+			} else {
+				lamps.WriteString(fmt.Sprintf(" %c  ", row[index]))
+			}
+		}
+
+		line("           %v", strings.TrimRight(lamps.String(), " "))
+	}
+
+	line("")
+	line("Plugs      %v", strings.Join(machine.PlugBoard.Plugs(), " "))
+	line("")
+	line("Input      %v", groupLetters(what.input))
+	line("Output     %v", groupLetters(what.output))
+	line("")
+	line("%v", what.status)
+	line("%v", tuiHelp)
+
+	_, writeError := io.WriteString(writer, builder.String())
+	return writeError
+}
+
+// groupLetters splits the letters into groups of five, the way messages were written down.
+func groupLetters(letters []byte) string {
+	var groups []string
+	for start := 0; start < len(letters); start += 5 {
+		groups = append(groups, string(letters[start:min(start+5, len(letters))]))
+	}
+
+	return strings.Join(groups, " ")
+}
+
+func letterIndex(c byte) int {
+	switch {
+	case c >= 'A' && c <= 'Z':
+		return int(c - 'A')
+
+	case c >= 'a' && c <= 'z':
+		return int(c - 'a')
+
+	default:
+		return -1
+	}
+}
+
+func wrapLetter(letter int) int {
+	limit := len(defs.UpperCase)
+	return (letter%limit + limit) % limit
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/r3db34n1an/enigma/pkg/enigma"
 	"github.com/r3db34n1an/enigma/pkg/settings"
+	"io"
 )
 
 type Enigma struct {
@@ -29,6 +30,24 @@ func (what *Enigma) Init() error {
 	return what.machine.Init()
 }
 
+func (what *Enigma) SetRegistry(registry *settings.Registry) error {
+	if what.machine == nil {
+		return fmt.Errorf("no enigma machine")
+	}
+
+	what.machine.SetRegistry(registry)
+	return nil
+}
+
+func (what *Enigma) SetTracer(tracer enigma.Tracer) error {
+	if what.machine == nil {
+		return fmt.Errorf("no enigma machine")
+	}
+
+	what.machine.SetTracer(tracer)
+	return nil
+}
+
 func (what *Enigma) Encrypt(plainText []byte, key string) ([]byte, error) {
 	if what.machine == nil {
 		return nil, fmt.Errorf("no enigma machine")
@@ -77,6 +96,38 @@ func (what *Enigma) DecryptWithSetting(cipherText []byte, setting *settings.Sett
 	return what.machine.DecryptWithSetting(cipherText, setting)
 }
 
+func (what *Enigma) NewTransformer(setting *settings.Setting) (*enigma.Transformer, error) {
+	if what.machine == nil {
+		return nil, fmt.Errorf("no enigma machine")
+	}
+
+	return what.machine.NewTransformer(setting)
+}
+
+func (what *Enigma) NewEncryptWriter(writer io.Writer, setting *settings.Setting) (io.WriteCloser, error) {
+	if what.machine == nil {
+		return nil, fmt.Errorf("no enigma machine")
+	}
+
+	return what.machine.NewEncryptWriter(writer, setting)
+}
+
+func (what *Enigma) NewDecryptReader(reader io.Reader, setting *settings.Setting) (io.Reader, error) {
+	if what.machine == nil {
+		return nil, fmt.Errorf("no enigma machine")
+	}
+
+	return what.machine.NewDecryptReader(reader, setting)
+}
+
+func (what *Enigma) NewSession(setting *settings.Setting) (*enigma.Session, error) {
+	if what.machine == nil {
+		return nil, fmt.Errorf("no enigma machine")
+	}
+
+	return what.machine.NewSession(setting)
+}
+
 func (what *Enigma) GenerateKey() (string, error) {
 	if what.machine == nil {
 		return "", fmt.Errorf("no enigma machine")
@@ -4,10 +4,13 @@ go 1.23.3
 
 require (
 	github.com/stretchr/testify v1.10.0
+	golang.org/x/term v0.34.0
+	golang.org/x/text v0.25.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
 )
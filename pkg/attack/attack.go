@@ -0,0 +1,101 @@
+// Package attack breaks messages without the key, on drums set up from the settings package.
+package attack
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+)
+
+// machine is a rotor order prepared for fast decryption of many keys: the scramblers by rotor offsets (window less
+// ring setting) and the stepping by window positions.
+type machine struct {
+	order     []string
+	reflector string
+	scramble  [settings.RotorPositions]settings.Permutation
+	next      [settings.RotorPositions]uint16
+}
+
+// key is a setting of a machine: the windows at the start of the message, the ring settings and the plug board.
+type key struct {
+	windows int
+	rings   [3]int
+	plugs   settings.Permutation
+}
+
+func newMachine(order []string, reflectorName string) (*machine, error) {
+	if len(order) != 3 {
+		return nil, fmt.Errorf("invalid rotor order %v, expected three rotors", order)
+	}
+
+	setting, settingError := settings.NewBareSetting(order, reflectorName)
+	if settingError != nil {
+		return nil, settingError
+	}
+
+	what := &machine{order: order, reflector: setting.Reflector.Name}
+	for position := range settings.RotorPositions {
+		setting.Rotors.SetIndex(position)
+		for in := range what.scramble[position] {
+			what.scramble[position][in] = uint8(setting.Scramble(in))
+		}
+
+		setting.Rotors.Move()
+		what.next[position] = uint16(setting.Rotors.Index())
+	}
+
+	return what, nil
+}
+
+// decrypt runs the letters through the machine at the key into out.
+func (what *machine) decrypt(letters []uint8, key *key, out []uint8) {
+	position := key.windows
+	for index, letter := range letters {
+		position = int(what.next[position])
+		left := (position/676 - key.rings[0] + 26) % 26
+		middle := (position/26%26 - key.rings[1] + 26) % 26
+		right := (position%26 - key.rings[2] + 26) % 26
+		out[index] = key.plugs[what.scramble[left*676+middle*26+right][key.plugs[letter]]]
+	}
+}
+
+// export returns the key as a setting of the package settings.
+func (what *machine) export(key *key) settings.ExportSetting {
+	exported := settings.ExportSetting{
+		Machine:   settings.DefaultMachine,
+		Reflector: what.reflector,
+		PlugBoard: make(settings.ExportPlugBoard),
+	}
+
+	windows := []int{key.windows / 676, key.windows / 26 % 26, key.windows % 26}
+	for index, name := range what.order {
+		exported.Rotors = append(exported.Rotors, settings.ExportRotor{
+			Name:        name,
+			Position:    string(defs.UpperCase[windows[index]]),
+			RingSetting: string(defs.UpperCase[key.rings[index]]),
+		})
+	}
+
+	for letter, partner := range key.plugs {
+		if int(partner) != letter {
+			exported.PlugBoard[string(defs.UpperCase[letter])] = string(defs.UpperCase[partner])
+		}
+	}
+
+	return exported
+}
+
+// normalize drops the spaces and converts the letters to 0-25.
+func normalize(text string) ([]uint8, error) {
+	letters, lettersError := defs.Letters(text)
+	if lettersError != nil {
+		return nil, lettersError
+	}
+
+	indexes := make([]uint8, len(letters))
+	for index := range letters {
+		indexes[index] = letters[index] - 'A'
+	}
+
+	return indexes, nil
+}
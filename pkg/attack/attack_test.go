@@ -0,0 +1,140 @@
+package attack
+
+import (
+	"context"
+	"github.com/r3db34n1an/enigma/pkg/enigma"
+	"github.com/r3db34n1an/enigma/pkg/scoring"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	testKey = "{rotors: [{name: II, position: K, ring_setting: D}, {name: V, position: X, ring_setting: B}, {name: III, position: P, ring_setting: C}], reflector: B, plug_board: {A: Q, Q: A, E: Z, Z: E, W: M, M: W, C: F, F: C}}"
+
+	testPlain = "ANXOBERKOMMANDOXDERXWEHRMACHTXDIEXFEINDLICHENXKRAEFTEXHABENXINXDERXNACHTXDIEXSTELLUNGENXAMXFLUSSXANGEGRIFFENX" +
+		"UNSEREXTRUPPENXHALTENXDIEXLINIEXNORDOSTWAERTSXDESXDORFESXVERSTAERKUNGXWIRDXBISXMORGENXFRUEHXERWARTETXMUNITION" +
+		"XUNDXVERPFLEGUNGXSINDXAUSREICHENDXWETTERXKLARXSICHTXGUTXKEINEXBESONDERENXEREIGNISSEXENDE"
+)
+
+func testMessage(t *testing.T) (*settings.Setting, string) {
+	var setting settings.Setting
+	assert.Nil(t, setting.Parse(testKey))
+
+	engine, engineError := enigma.NewEnigma(false, false)
+	assert.Nil(t, engineError)
+
+	cipherText, encryptError := engine.EncryptWithSetting([]byte(testPlain), &setting)
+	assert.Nil(t, encryptError)
+
+	return &setting, string(cipherText)
+}
+
+func TestCiphertextOnly(t *testing.T) {
+	setting, cipherText := testMessage(t)
+
+	// the embedded German, trained on texts that have nothing in common with the message
+	german, germanError := scoring.German()
+	assert.Nil(t, germanError)
+	scorers := german.Scorers()
+
+	var rotorsDone, plugsDone atomic.Int32
+	attack := &CiphertextOnly{
+		Orders:     [][]string{{"I", "II", "III"}, {"II", "V", "III"}},
+		Candidates: 5,
+		Scorers:    scorers,
+		Progress: func(progress Progress) {
+			switch progress.Stage {
+			case "rotors":
+				rotorsDone.Add(1)
+				assert.Equal(t, 2, progress.Total)
+
+			case "plugs":
+				plugsDone.Add(1)
+				assert.Equal(t, 5, progress.Total)
+			}
+		},
+	}
+
+	result, attackError := attack.Run(context.Background(), cipherText)
+	assert.Nil(t, attackError)
+	assert.Equal(t, testPlain, result.PlainText)
+	assert.Equal(t, int32(2), rotorsDone.Load())
+	assert.Equal(t, int32(5), plugsDone.Load())
+
+	// the rotor order and the plugs of the key
+	for index, rotor := range result.Setting.Rotors {
+		assert.Equal(t, setting.Rotors[index].Name, rotor.Name)
+	}
+
+	assert.Equal(t, setting.PlugBoard.Export(), result.Setting.PlugBoard)
+
+	// the setting decrypts on the Enigma
+	var found settings.Setting
+	assert.Nil(t, found.Import(result.Setting))
+	engine, engineError := enigma.NewEnigma(false, false)
+	assert.Nil(t, engineError)
+	plainText, decryptError := engine.DecryptWithSetting([]byte(strings.Join(strings.Fields(cipherText), "")), &found)
+	assert.Nil(t, decryptError)
+	assert.Equal(t, testPlain, strings.Join(strings.Fields(string(plainText)), ""))
+
+	// the same by default
+	defaults := &CiphertextOnly{Orders: attack.Orders, Candidates: attack.Candidates}
+	result, attackError = defaults.Run(context.Background(), cipherText)
+	assert.Nil(t, attackError)
+	assert.Equal(t, testPlain, result.PlainText)
+
+	// cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, attackError = attack.Run(ctx, cipherText)
+	assert.ErrorIs(t, attackError, context.Canceled)
+
+	// invalid input
+	_, attackError = attack.Run(context.Background(), "ABC1")
+	assert.NotNil(t, attackError)
+
+	_, attackError = (&CiphertextOnly{Orders: [][]string{{"I", "II"}}}).Run(context.Background(), cipherText)
+	assert.NotNil(t, attackError)
+
+	_, attackError = (&CiphertextOnly{Rotors: []string{"I", "II"}}).Run(context.Background(), cipherText)
+	assert.NotNil(t, attackError)
+}
+
+func TestMachine(t *testing.T) {
+	// the prepared drums decrypt like the Enigma
+	setting, cipherText := testMessage(t)
+	rotorMachine, machineError := newMachine([]string{"II", "V", "III"}, "B")
+	assert.Nil(t, machineError)
+
+	letters, lettersError := normalize(cipherText)
+	assert.Nil(t, lettersError)
+
+	testKey := key{plugs: setting.PlugBoard.Mapping}
+	for index, rotor := range setting.Rotors {
+		testKey.windows = testKey.windows*26 + rotor.Position
+		testKey.rings[index] = rotor.RingSetting
+	}
+
+	out := make([]uint8, len(letters))
+	rotorMachine.decrypt(letters, &testKey, out)
+	assert.Equal(t, scoring.Letters(testPlain), out)
+
+	exported := rotorMachine.export(&testKey)
+	assert.Equal(t, setting.Export(), exported)
+
+	// replugging keeps a valid plug board
+	plugs := settings.IdentityPermutation()
+	for _, pair := range [][2]int{{0, 1}, {2, 3}, {0, 2}, {1, 3}, {0, 2}} {
+		plugs, _ = replug(plugs, pair[0], pair[1], 10)
+		assert.True(t, plugs.IsValid())
+		for letter, partner := range plugs {
+			assert.Equal(t, uint8(letter), plugs[partner])
+		}
+	}
+
+	_, ok := replug(settings.IdentityPermutation(), 0, 1, 0)
+	assert.False(t, ok)
+}
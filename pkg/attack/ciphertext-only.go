@@ -0,0 +1,405 @@
+package attack
+
+import (
+	"context"
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/enigma"
+	"github.com/r3db34n1an/enigma/pkg/scoring"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultCandidates is the number of rotor settings kept from the search by index of coincidence.
+	DefaultCandidates = 20
+
+	// DefaultPlugPairs is the most plug board pairs the hill climbing connects.
+	DefaultPlugPairs = 10
+)
+
+// CiphertextOnly is the attack of Gillogly, with the plug board search of Weierud and Sullivan. Without plugs the
+// index of coincidence of a decrypt rises when the rotors are right, so all rotor orders and start positions are
+// tried with the ring settings at A and the best kept. The ring settings of the right and middle rotor are then
+// turned one by one, and the plug board is found by hill climbing, scored by each of Scorers in turn.
+type CiphertextOnly struct {
+	Rotors     []string         // Walzen, settings.DefaultRotors if empty
+	Reflector  string           // Umkehrwalze, settings.DefaultReflector if empty
+	Orders     [][]string       // rotor orders to try, every order of three different Rotors if empty
+	Candidates int              // rotor settings kept after the search, DefaultCandidates if 0
+	PlugPairs  int              // most plug board pairs, DefaultPlugPairs if 0, none if negative
+	Scorers    []scoring.Scorer // scores of the hill climbing, from coarse to fine, the German n-grams if empty
+	Workers    int              // searches run in parallel, the number of CPUs if 0
+	Progress   func(Progress)   // called after every rotor order and candidate, may be nil
+}
+
+// Progress tells how far a stage of the attack got.
+type Progress struct {
+	Stage string // "rotors" or "plugs"
+	Done  int
+	Total int
+}
+
+// Result is the best setting found.
+type Result struct {
+	Setting   settings.ExportSetting
+	PlainText string
+	Score     float64 // of the last scorer
+}
+
+// candidate is a key with its machine and score.
+type candidate struct {
+	machine *machine
+	key     key
+	score   float64
+}
+
+// Run attacks the cipher text until done or the context is cancelled.
+func (what *CiphertextOnly) Run(ctx context.Context, cipherText string) (*Result, error) {
+	letters, lettersError := normalize(cipherText)
+	if lettersError != nil {
+		return nil, fmt.Errorf("invalid cipher text: %v", lettersError)
+	}
+
+	if len(letters) < 2 {
+		return nil, fmt.Errorf("cipher text too short")
+	}
+
+	scorers, scorersError := what.scorers()
+	if scorersError != nil {
+		return nil, scorersError
+	}
+
+	candidates, searchError := what.searchRotors(ctx, letters)
+	if searchError != nil {
+		return nil, searchError
+	}
+
+	climbError := what.parallel(ctx, "plugs", len(candidates), func(index int) error {
+		candidate := candidates[index]
+		candidate.findRings(letters)
+		candidate.climb(letters, scorers, what.plugPairs())
+
+		return nil
+	})
+	if climbError != nil {
+		return nil, climbError
+	}
+
+	best := slices.MaxFunc(candidates, func(one *candidate, two *candidate) int {
+		switch {
+		case one.score < two.score:
+			return -1
+
+		case one.score > two.score:
+			return 1
+		}
+
+		return 0
+	})
+
+	return best.result(letters)
+}
+
+// searchRotors decrypts at every rotor order and start position with the ring settings at A and no plugs and keeps
+// the candidates with the highest index of coincidence.
+func (what *CiphertextOnly) searchRotors(ctx context.Context, letters []uint8) ([]*candidate, error) {
+	rotorOrders := what.Orders
+	if len(rotorOrders) == 0 {
+		rotors := what.Rotors
+		if len(rotors) == 0 {
+			rotors = settings.DefaultRotors
+		}
+
+		rotorOrders = settings.RotorOrders(rotors)
+	}
+
+	if len(rotorOrders) == 0 {
+		return nil, fmt.Errorf("no rotor orders, expected at least three rotors")
+	}
+
+	reflector := what.Reflector
+	if len(reflector) == 0 {
+		reflector = settings.DefaultReflector
+	}
+
+	count := what.Candidates
+	if count <= 0 {
+		count = DefaultCandidates
+	}
+
+	var lock sync.Mutex
+	var best []*candidate
+	searchError := what.parallel(ctx, "rotors", len(rotorOrders), func(index int) error {
+		rotorMachine, machineError := newMachine(rotorOrders[index], reflector)
+		if machineError != nil {
+			return machineError
+		}
+
+		var found []*candidate
+		out := make([]uint8, len(letters))
+		for windows := range settings.RotorPositions {
+			if windows%676 == 0 && ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			next := &candidate{machine: rotorMachine, key: key{windows: windows, plugs: settings.IdentityPermutation()}}
+			rotorMachine.decrypt(letters, &next.key, out)
+			next.score = scoring.IndexOfCoincidence(out)
+			found = keepBest(found, next, count)
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		for _, next := range found {
+			best = keepBest(best, next, count)
+		}
+
+		return nil
+	})
+	if searchError != nil {
+		return nil, searchError
+	}
+
+	return best, nil
+}
+
+// parallel runs the work on the workers and reports the progress of the stage.
+func (what *CiphertextOnly) parallel(ctx context.Context, stage string, total int, work func(index int) error) error {
+	workers := what.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	queue := make(chan int)
+	var lock sync.Mutex
+	var firstError error
+	done := 0
+
+	var waitGroup sync.WaitGroup
+	for range min(workers, total) {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for index := range queue {
+				workError := work(index)
+
+				lock.Lock()
+				if workError != nil && firstError == nil {
+					firstError = workError
+				}
+
+				done++
+				if what.Progress != nil {
+					what.Progress(Progress{Stage: stage, Done: done, Total: total})
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for index := range total {
+		if ctx.Err() != nil {
+			break
+		}
+
+		queue <- index
+	}
+
+	close(queue)
+	waitGroup.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return firstError
+}
+
+// scorers starts the default climb on the index of coincidence, n-grams of a wrong plug board score too low to
+// tell the pairs apart.
+func (what *CiphertextOnly) scorers() ([]scoring.Scorer, error) {
+	if len(what.Scorers) > 0 {
+		return what.Scorers, nil
+	}
+
+	german, germanError := scoring.German()
+	if germanError != nil {
+		return nil, germanError
+	}
+
+	return append([]scoring.Scorer{scoring.Coincidence{}}, german.Scorers()...), nil
+}
+
+func (what *CiphertextOnly) plugPairs() int {
+	switch {
+	case what.PlugPairs == 0:
+		return DefaultPlugPairs
+
+	case what.PlugPairs < 0:
+		return 0
+	}
+
+	return what.PlugPairs
+}
+
+// keepBest adds the candidate to the list sorted by score, highest first, and keeps at most count.
+func keepBest(best []*candidate, next *candidate, count int) []*candidate {
+	if len(best) == count && next.score <= best[len(best)-1].score {
+		return best
+	}
+
+	index, _ := slices.BinarySearchFunc(best, next.score, func(one *candidate, score float64) int {
+		switch {
+		case one.score > score:
+			return -1
+
+		case one.score < score:
+			return 1
+		}
+
+		return 0
+	})
+
+	best = slices.Insert(best, index, next)
+
+	return best[:min(len(best), count)]
+}
+
+// findRings turns the ring settings of the right and then the middle rotor, moving the windows along so the rotor
+// offsets stay, and keeps the one with the highest index of coincidence. Only the turnovers change, the ring setting
+// of the left rotor does not matter.
+func (what *candidate) findRings(letters []uint8) {
+	out := make([]uint8, len(letters))
+	for _, slot := range []int{2, 1} {
+		unit := []int{676, 26, 1}[slot]
+		best := what.key
+		bestScore := what.score
+		for ring := 1; ring < 26; ring++ {
+			trial := what.key
+			window := trial.windows / unit % 26
+			trial.windows += ((window+ring)%26 - window) * unit
+			trial.rings[slot] = ring
+
+			what.machine.decrypt(letters, &trial, out)
+			score := scoring.IndexOfCoincidence(out)
+			if score > bestScore {
+				best = trial
+				bestScore = score
+			}
+		}
+
+		what.key = best
+		what.score = bestScore
+	}
+}
+
+// climb connects, swaps and removes plugs while the score rises, for every scorer in turn.
+func (what *candidate) climb(letters []uint8, scorers []scoring.Scorer, plugPairs int) {
+	out := make([]uint8, len(letters))
+	score := func(plugs settings.Permutation) float64 {
+		trial := what.key
+		trial.plugs = plugs
+		what.machine.decrypt(letters, &trial, out)
+
+		return scorers[0].Score(out)
+	}
+
+	for len(scorers) > 0 {
+		what.score = score(what.key.plugs)
+		for {
+			best := what.key.plugs
+			bestScore := what.score
+			for one := range 26 {
+				for two := one + 1; two < 26; two++ {
+					trial, ok := replug(what.key.plugs, one, two, plugPairs)
+					if !ok {
+						continue
+					}
+
+					trialScore := score(trial)
+					if trialScore > bestScore {
+						best = trial
+						bestScore = trialScore
+					}
+				}
+			}
+
+			if best == what.key.plugs {
+				break
+			}
+
+			what.key.plugs = best
+			what.score = bestScore
+		}
+
+		scorers = scorers[1:]
+	}
+}
+
+// replug changes the plug board for the two letters: a cable between them is removed, free letters are connected,
+// and cables from either letter are moved so the two are connected and their old partners are.
+func replug(plugs settings.Permutation, one int, two int, plugPairs int) (settings.Permutation, bool) {
+	partnerOne, partnerTwo := int(plugs[one]), int(plugs[two])
+	switch {
+	case partnerOne == two:
+		plugs[one], plugs[two] = uint8(one), uint8(two)
+
+	case partnerOne == one && partnerTwo == two:
+		pairs := 0
+		for letter, partner := range plugs {
+			if int(partner) > letter {
+				pairs++
+			}
+		}
+
+		if pairs >= plugPairs {
+			return plugs, false
+		}
+
+		plugs[one], plugs[two] = uint8(two), uint8(one)
+
+	default:
+		plugs[partnerOne], plugs[partnerTwo] = uint8(partnerOne), uint8(partnerTwo)
+		plugs[one], plugs[two] = uint8(two), uint8(one)
+		if partnerOne != one && partnerTwo != two {
+			plugs[partnerOne], plugs[partnerTwo] = uint8(partnerTwo), uint8(partnerOne)
+		}
+	}
+
+	return plugs, true
+}
+
+// result decrypts the message on the Enigma at the candidate's setting.
+func (what *candidate) result(letters []uint8) (*Result, error) {
+	exported := what.machine.export(&what.key)
+
+	var setting settings.Setting
+	importError := setting.Import(exported)
+	if importError != nil {
+		return nil, fmt.Errorf("invalid result: %v", importError)
+	}
+
+	engine, engineError := enigma.NewEnigma(false, false)
+	if engineError != nil {
+		return nil, engineError
+	}
+
+	cipherText := make([]byte, len(letters))
+	for index, letter := range letters {
+		cipherText[index] = 'A' + letter
+	}
+
+	plainText, decryptError := engine.DecryptWithSetting(cipherText, &setting)
+	if decryptError != nil {
+		return nil, decryptError
+	}
+
+	return &Result{
+		Setting:   exported,
+		PlainText: strings.Join(strings.Fields(string(plainText)), ""),
+		Score:     what.score,
+	}, nil
+}
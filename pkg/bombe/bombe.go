@@ -0,0 +1,369 @@
+package bombe
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"github.com/r3db34n1an/enigma/pkg/enigma"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"math/bits"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// allWires has a live wire for every letter.
+const allWires = 1<<26 - 1
+
+// Bombe holds the drums to try. Like the real machine it only turns the right rotor along the crib, a turnover of the
+// middle rotor within the crib hides the correct stop.
+type Bombe struct {
+	Rotors    []string   // Walzen, settings.DefaultRotors if empty
+	Reflector string     // Umkehrwalze, settings.DefaultReflector if empty
+	Orders    [][]string // rotor orders to run, every order of three different Rotors if empty
+	Workers   int        // rotor orders run in parallel, the number of CPUs if 0
+}
+
+// Stop is a position where the menu did not contradict itself, checked on the Enigma.
+type Stop struct {
+	Order     []string // Walzenlage
+	Offsets   []int    // rotor positions less ring settings at the first letter of the crib, before stepping
+	Reflector string
+	Centre    int      // Testregister
+	Stecker   int      // partner of the Testregister
+	Plugs     []string // Steckerverbindungen implied for the letters reached by the menu
+	Decrypt   string   // the message from the crib on, decrypted with Setting
+	Matches   int      // letters of the decrypt matching the crib, all of them for a good stop
+}
+
+// edge is a link of the menu seen from one of its letters.
+type edge struct {
+	link  int
+	other int
+}
+
+// order is a rotor order set up for a run.
+type order struct {
+	index   int
+	names   []string
+	setting *settings.Setting // bare setting of the rotors, positions changed by the run
+}
+
+// Run sweeps the rotor orders and start positions with the menu of the cipher text and returns the stops, ordered by
+// rotor order and position. Every stop is decrypted on the Enigma, the ones matching the whole crib are the keys.
+func (what *Bombe) Run(menu *Menu, cipherText string) ([]Stop, error) {
+	if menu == nil || len(menu.Links) == 0 {
+		return nil, fmt.Errorf("no menu")
+	}
+
+	letters, lettersError := defs.Letters(cipherText)
+	if lettersError != nil {
+		return nil, fmt.Errorf("invalid cipher text: %v", lettersError)
+	}
+
+	if menu.Offset+len(menu.CipherText) > len(letters) || letters[menu.Offset:menu.Offset+len(menu.CipherText)] != menu.CipherText {
+		return nil, fmt.Errorf("menu not made from the cipher text")
+	}
+
+	orders, ordersError := what.orders()
+	if ordersError != nil {
+		return nil, ordersError
+	}
+
+	workers := what.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var edges [26][]edge
+	for index, link := range menu.Links {
+		edges[link.Plain] = append(edges[link.Plain], edge{link: index, other: link.Cipher})
+		edges[link.Cipher] = append(edges[link.Cipher], edge{link: index, other: link.Plain})
+	}
+
+	queue := make(chan *order)
+	results := make([][]Stop, len(orders))
+	errors := make([]error, len(orders))
+
+	var waitGroup sync.WaitGroup
+	for range min(workers, len(orders)) {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for next := range queue {
+				results[next.index], errors[next.index] = runOrder(menu, &edges, next)
+			}
+		}()
+	}
+
+	for _, next := range orders {
+		queue <- next
+	}
+
+	close(queue)
+	waitGroup.Wait()
+
+	for _, runError := range errors {
+		if runError != nil {
+			return nil, runError
+		}
+	}
+
+	engine, engineError := enigma.NewEnigma(false, false)
+	if engineError != nil {
+		return nil, engineError
+	}
+
+	var stops []Stop
+	for _, result := range results {
+		for index := range result {
+			checkError := result[index].check(engine, menu, letters[menu.Offset:])
+			if checkError != nil {
+				return nil, checkError
+			}
+
+			stops = append(stops, result[index])
+		}
+	}
+
+	return stops, nil
+}
+
+// orders sets up the scrambler of every rotor order to run.
+func (what *Bombe) orders() ([]*order, error) {
+	names := what.Orders
+	if len(names) == 0 {
+		rotors := what.Rotors
+		if len(rotors) == 0 {
+			rotors = settings.DefaultRotors
+		}
+
+		names = settings.RotorOrders(rotors)
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no rotor orders, expected at least three rotors")
+	}
+
+	reflector := what.Reflector
+	if len(reflector) == 0 {
+		reflector = settings.DefaultReflector
+	}
+
+	var orders []*order
+	for index, orderNames := range names {
+		if len(orderNames) != 3 {
+			return nil, fmt.Errorf("invalid rotor order %v, expected three rotors", orderNames)
+		}
+
+		setting, settingError := settings.NewBareSetting(orderNames, reflector)
+		if settingError != nil {
+			return nil, settingError
+		}
+
+		orders = append(orders, &order{index: index, names: orderNames, setting: setting})
+	}
+
+	return orders, nil
+}
+
+// runOrder tries the start positions of a rotor order. For every left and middle position the scramblers of the 26
+// right positions are looked up once, the links of the menu use them shifted by their position in the crib.
+func runOrder(menu *Menu, edges *[26][]edge, next *order) ([]Stop, error) {
+	scrambler, scramblerError := settings.NewScrambler(next.setting)
+	if scramblerError != nil {
+		return nil, scramblerError
+	}
+
+	rotors := next.setting.Rotors
+
+	var stops []Stop
+	var drums [26]*settings.Permutation
+	links := make([]*settings.Permutation, len(menu.Links))
+	for left := range 26 {
+		for middle := range 26 {
+			for right := range drums {
+				rotors[0].Position = left
+				rotors[1].Position = middle
+				rotors[2].Position = right
+				drums[right] = scrambler.Permutation(next.setting)
+			}
+
+			for right := range drums {
+				// the machine steps before every letter
+				for index, link := range menu.Links {
+					links[index] = drums[(right+link.Position+1)%26]
+				}
+
+				for _, stecker := range test(menu.Centre, edges, links) {
+					stops = append(stops, Stop{
+						Order:     slices.Clone(next.names),
+						Offsets:   []int{left, middle, right},
+						Reflector: next.setting.Reflector.Name,
+						Centre:    menu.Centre,
+						Stecker:   stecker.letter,
+						Plugs:     stecker.plugs,
+					})
+				}
+			}
+		}
+	}
+
+	return stops, nil
+}
+
+// stecker is a consistent partner of the Testregister and the plugs it implies.
+type stecker struct {
+	letter int
+	plugs  []string
+}
+
+// test energises a wire of the Testregister. If the current reaches every wire of it, every partner is contradicted
+// and the Bombe runs on. Otherwise it stops, and each partner whose wires carry at most one live wire per letter is
+// kept, like the checks of the operators at the machine gun.
+func test(centre int, edges *[26][]edge, links []*settings.Permutation) []stecker {
+	if energise(centre, edges, links, 0, true)[centre] == allWires {
+		return nil
+	}
+
+	var found []stecker
+	for letter := range 26 {
+		live := energise(centre, edges, links, letter, false)
+
+		var plugs []string
+		consistent := true
+		for register, wires := range live {
+			if bits.OnesCount32(wires) > 1 {
+				consistent = false
+				break
+			}
+
+			partner := bits.TrailingZeros32(wires)
+			if wires != 0 && partner > register {
+				plugs = append(plugs, string([]byte{defs.UpperCase[register], defs.UpperCase[partner]}))
+			}
+		}
+
+		if consistent {
+			found = append(found, stecker{letter: letter, plugs: plugs})
+		}
+	}
+
+	return found
+}
+
+// energise puts current on a wire of the Testregister and follows it through the scramblers of the links and the
+// diagonal board, which connects wire b of register a to wire a of register b. The result has a bit per live wire,
+// with untilFull it ends as soon as every wire of the Testregister is live.
+func energise(centre int, edges *[26][]edge, links []*settings.Permutation, wire int, untilFull bool) [26]uint32 {
+	var live [26]uint32
+	var queue [26 * 26]uint16 // register and wire of the wires lit but not followed yet
+	length := 0
+
+	light := func(register int, wire int) {
+		if live[register]&(1<<wire) == 0 {
+			live[register] |= 1 << wire
+			queue[length] = uint16(register<<5 | wire)
+			length++
+		}
+	}
+
+	light(centre, wire)
+	for length > 0 {
+		length--
+		register, current := int(queue[length]>>5), int(queue[length]&31)
+
+		light(current, register)
+		for _, next := range edges[register] {
+			light(next.other, int(links[next.link][current]))
+		}
+
+		if untilFull && live[centre] == allWires {
+			break
+		}
+	}
+
+	return live
+}
+
+// Positions returns the offsets as letters, the window positions with all ring settings at A.
+func (what *Stop) Positions() string {
+	positions := make([]byte, len(what.Offsets))
+	for index, offset := range what.Offsets {
+		positions[index] = defs.UpperCase[offset]
+	}
+
+	return string(positions)
+}
+
+func (what *Stop) String() string {
+	return fmt.Sprintf("%v %v %c=%c %v", strings.Join(what.Order, " "), what.Positions(), defs.UpperCase[what.Centre],
+		defs.UpperCase[what.Stecker], strings.Join(what.Plugs, " "))
+}
+
+// Setting returns an Enigma setting at the first letter of the crib. The Bombe only finds the rotor offsets, so the
+// middle and right rotors get ring settings putting their notches as far away as possible, no turnover happens within
+// MaxCribLength letters for single notch rotors.
+func (what *Stop) Setting() (*settings.Setting, error) {
+	exported := settings.ExportSetting{
+		Reflector: what.Reflector,
+	}
+
+	for index, name := range what.Order {
+		rotor, rotorError := settings.GetRotor(name)
+		if rotorError != nil {
+			return nil, rotorError
+		}
+
+		window := what.Offsets[index]
+		if index > 0 && len(rotor.Notches) > 0 {
+			window = (rotor.Notches[0] + 1) % 26
+		}
+
+		exported.Rotors = append(exported.Rotors, settings.ExportRotor{
+			Name:        name,
+			Position:    string(defs.UpperCase[window]),
+			RingSetting: string(defs.UpperCase[(window-what.Offsets[index]+26)%26]),
+		})
+	}
+
+	var plugBoard settings.PlugBoard
+	plugError := plugBoard.Parse(strings.Join(what.Plugs, " "))
+	if plugError != nil {
+		return nil, fmt.Errorf("invalid plugs: %v", plugError)
+	}
+
+	exported.PlugBoard = plugBoard.Export()
+
+	var setting settings.Setting
+	importError := setting.Import(exported)
+	if importError != nil {
+		return nil, fmt.Errorf("invalid stop: %v", importError)
+	}
+
+	return &setting, nil
+}
+
+// check decrypts the message from the crib on with the stop's setting and counts the letters matching the crib.
+// Letters off the menu may be plugged differently, so only a full match is certain.
+func (what *Stop) check(engine *enigma.Enigma, menu *Menu, message string) error {
+	setting, settingError := what.Setting()
+	if settingError != nil {
+		return settingError
+	}
+
+	plainText, decryptError := engine.DecryptWithSetting([]byte(message), setting)
+	if decryptError != nil {
+		return decryptError
+	}
+
+	what.Decrypt = strings.Join(strings.Fields(string(plainText)), "")
+	what.Matches = 0
+	for index := range min(len(what.Decrypt), len(menu.Crib)) {
+		if what.Decrypt[index] == menu.Crib[index] {
+			what.Matches++
+		}
+	}
+
+	return nil
+}
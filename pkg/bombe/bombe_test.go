@@ -0,0 +1,234 @@
+package bombe
+
+import (
+	"github.com/r3db34n1an/enigma/pkg/enigma"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const (
+	testKey   = "{rotors: [{name: II, position: M, ring_setting: C}, {name: V, position: R, ring_setting: F}, {name: III, position: A, ring_setting: K}], reflector: B, plug_board: {A: Q, Q: A, E: Z, Z: E, R: T, T: R, W: M, M: W, O: L, L: O, N: X, X: N}}"
+	testPlain = "WETTERVORHERSAGEXBISKAYAXNEBELXSICHTWEITEXZWEIHUNDERTMETER"
+	testCrib  = "WETTERVORHERSAGEXBIS"
+)
+
+func testCipherText(t *testing.T) string {
+	engine, engineError := enigma.NewEnigma(false, false)
+	assert.Nil(t, engineError)
+
+	cipherText, encryptError := engine.Encrypt([]byte(testPlain), testKey)
+	assert.Nil(t, encryptError)
+
+	return string(cipherText)
+}
+
+func TestMenu(t *testing.T) {
+	cipherText := testCipherText(t)
+
+	menu, menuError := NewMenu(testCrib, cipherText, 0)
+	assert.Nil(t, menuError)
+	assert.Equal(t, len(testCrib), len(menu.Links))
+	assert.Equal(t, strings.ReplaceAll(cipherText, " ", "")[:len(testCrib)], menu.CipherText)
+	assert.Equal(t, len(menu.Links)-len(menu.Letters())+len(menu.Components()), menu.Loops())
+	assert.Greater(t, menu.Loops(), 1)
+	assert.Contains(t, menu.Letters(), menu.Centre)
+
+	// the letters of a small hand made menu: a triangle and a separate link
+	small := &Menu{Links: []Link{{0, 0, 1}, {1, 1, 2}, {2, 2, 0}, {3, 3, 4}}}
+	small.Centre = small.centre()
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, small.Letters())
+	assert.Equal(t, [][]int{{0, 1, 2}, {3, 4}}, small.Components())
+	assert.Equal(t, 1, small.Loops())
+	assert.Contains(t, []int{0, 1, 2}, small.Centre)
+
+	// the machine never puts a letter onto itself
+	for offset := range len(testPlain) - len(testCrib) + 1 {
+		_, clashError := NewMenu(testCrib, cipherText, offset)
+		if clashError != nil {
+			assert.Contains(t, clashError.Error(), "onto itself")
+		}
+	}
+
+	for _, invalid := range []struct {
+		crib   string
+		offset int
+	}{
+		{"", 0},
+		{strings.Repeat("A", MaxCribLength+1), 0},
+		{testCrib, -1},
+		{testCrib, len(testPlain)},
+		{"WETTER1", 0},
+	} {
+		_, menuError = NewMenu(invalid.crib, cipherText, invalid.offset)
+		assert.NotNil(t, menuError, invalid.crib)
+	}
+}
+
+func TestBombe(t *testing.T) {
+	engine, engineError := enigma.NewEnigma(false, false)
+	assert.Nil(t, engineError)
+
+	// generated traffic, on a day where the middle rotor stays put along the crib
+	generator := settings.NewGenerator(1941)
+	generator.Reflectors = []string{settings.DefaultReflector}
+
+	var setting *settings.Setting
+	for setting == nil {
+		next, generateError := generator.Next()
+		assert.Nil(t, generateError)
+
+		session, sessionError := engine.NewSession(next.Copy())
+		assert.Nil(t, sessionError)
+		start := session.Positions()
+		_, encryptError := session.Encrypt([]byte(testCrib))
+		assert.Nil(t, encryptError)
+		if session.Positions()[:2] == start[:2] {
+			setting = next
+		}
+	}
+
+	cipherText, encryptError := engine.EncryptWithSetting([]byte(testPlain), setting.Copy())
+	assert.Nil(t, encryptError)
+
+	menu, menuError := NewMenu(testCrib, string(cipherText), 0)
+	assert.Nil(t, menuError)
+
+	var order []string
+	var offsets []int
+	for _, rotor := range setting.Rotors {
+		order = append(order, rotor.Name)
+		offsets = append(offsets, (rotor.Position-rotor.RingSetting+26)%26)
+	}
+
+	// every order of the rotors of the day, all 60 orders of the Enigma I take ten times as long
+	bombe := &Bombe{Rotors: order}
+	stops, runError := bombe.Run(menu, string(cipherText))
+	assert.Nil(t, runError)
+	assert.NotEmpty(t, stops)
+
+	// the key is among the stops with the plugs of the key, and decrypts the crib on the Enigma
+	found := false
+	for _, stop := range stops {
+		if !slices.Equal(stop.Order, order) || !slices.Equal(stop.Offsets, offsets) {
+			continue
+		}
+
+		found = true
+		assert.Equal(t, int(setting.PlugBoard.Mapping[menu.Centre]), stop.Stecker)
+		for _, plug := range stop.Plugs {
+			assert.Equal(t, plug[1]-'A', setting.PlugBoard.Mapping[plug[0]-'A'], plug)
+		}
+
+		assert.Equal(t, len(testCrib), stop.Matches)
+		assert.True(t, strings.HasPrefix(stop.Decrypt, testCrib), stop.Decrypt)
+	}
+
+	assert.True(t, found, "stops %v", stops)
+
+	// wrong stops rarely survive the check on the Enigma
+	verified := 0
+	for _, stop := range stops {
+		if stop.Matches == len(testCrib) {
+			verified++
+		}
+	}
+
+	assert.Less(t, verified, 5)
+
+	for _, invalid := range []*Bombe{
+		{Orders: [][]string{{"I", "II"}}},
+		{Orders: [][]string{{"I", "II", "X"}}},
+		{Rotors: []string{"I", "II"}},
+		{Reflector: "X"},
+	} {
+		_, runError = invalid.Run(menu, string(cipherText))
+		assert.NotNil(t, runError)
+	}
+
+	_, runError = (&Bombe{}).Run(nil, string(cipherText))
+	assert.NotNil(t, runError)
+
+	_, runError = (&Bombe{}).Run(menu, testPlain)
+	assert.NotNil(t, runError)
+
+	_, runError = (&Bombe{}).Run(menu, "ABC-DEF")
+	assert.NotNil(t, runError)
+}
+
+func TestFindCribs(t *testing.T) {
+	cipherText := testCipherText(t)
+	letters := strings.ReplaceAll(cipherText, " ", "")
+
+	placements, findError := FindCribs(testCrib, cipherText, nil)
+	assert.Nil(t, findError)
+
+	// exactly the offsets without a letter put onto itself, the true one among them
+	var offsets []int
+	for offset := range len(letters) - len(testCrib) + 1 {
+		_, menuError := NewMenu(testCrib, letters, offset)
+		if menuError == nil {
+			offsets = append(offsets, offset)
+		}
+	}
+
+	var found []int
+	for index, placement := range placements {
+		found = append(found, placement.Offset)
+		assert.Equal(t, placement.Offset, placement.Menu.Offset)
+		if index > 0 {
+			assert.LessOrEqual(t, placement.Menu.Loops(), placements[index-1].Menu.Loops())
+		}
+	}
+
+	slices.Sort(found)
+	assert.Equal(t, offsets, found)
+	assert.Contains(t, found, 0)
+
+	// position constraints
+	placements, findError = FindCribs(testCrib, cipherText, AtStart())
+	assert.Nil(t, findError)
+	assert.Equal(t, 1, len(placements))
+	assert.Equal(t, 0, placements[0].Offset)
+
+	placements, findError = FindCribs("METER", cipherText, AtEnd())
+	assert.Nil(t, findError)
+	assert.Equal(t, 1, len(placements))
+	assert.Equal(t, len(letters)-5, placements[0].Offset)
+
+	placements, findError = FindCribs("NEBEL", cipherText, Within(10, 30))
+	assert.Nil(t, findError)
+	for _, placement := range placements {
+		assert.GreaterOrEqual(t, placement.Offset, 10)
+		assert.LessOrEqual(t, placement.Offset, 30)
+	}
+
+	// the standard cribs ranked together
+	placements, findError = FindAllCribs(append([]string{testCrib}, StandardCribs...), cipherText, nil)
+	assert.Nil(t, findError)
+	assert.True(t, slices.ContainsFunc(placements, func(placement Placement) bool {
+		return placement.Crib == testCrib && placement.Offset == 0
+	}))
+	for index := 1; index < len(placements); index++ {
+		previous, current := placements[index-1], placements[index]
+		assert.True(t, previous.Menu.Loops() > current.Menu.Loops() || previous.Menu.Loops() == current.Menu.Loops() &&
+			len(previous.Crib) >= len(current.Crib), current.String())
+	}
+
+	// a crib longer than a menu is placed as a whole
+	placements, findError = FindCribs(testPlain, cipherText, nil)
+	assert.Nil(t, findError)
+	assert.Equal(t, 1, len(placements))
+	assert.Equal(t, testPlain, placements[0].Crib)
+	assert.Equal(t, testPlain[:MaxCribLength], placements[0].Menu.Crib)
+
+	for _, invalid := range []string{"", "WETTER1"} {
+		_, findError = FindCribs(invalid, cipherText, nil)
+		assert.NotNil(t, findError, invalid)
+	}
+
+	_, findError = FindCribs(testCrib, "ABC-DEF", nil)
+	assert.NotNil(t, findError)
+}
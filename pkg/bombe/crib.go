@@ -0,0 +1,125 @@
+package bombe
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"slices"
+)
+
+// StandardCribs are phrases of German traffic that Hut 6 and Hut 8 tried first, written the way Enigma.Sanitize
+// writes them.
+var StandardCribs = []string{
+	"ANX",
+	"WETTERVORHERSAGE",
+	"WETTERBERICHT",
+	"KEINEBESONDERENEREIGNISSE",
+	"OBERKOMMANDODERWEHRMACHT",
+	"FUEHRERHAUPTQUARTIER",
+	"HEILHITLER",
+	"ANGRIFF",
+	"FEINDLICHE",
+	"GENERALSTAB",
+}
+
+// Placement is an offset where a crib fits the message without putting a letter onto itself.
+type Placement struct {
+	Crib   string
+	Offset int
+	Menu   *Menu // of the first MaxCribLength letters of the crib
+}
+
+// Constraint limits where a crib may start, given its length and the length of the message in letters.
+type Constraint func(offset int, cribLength int, messageLength int) bool
+
+// AtStart keeps cribs at the start of the message, like addresses.
+func AtStart() Constraint {
+	return Within(0, 0)
+}
+
+// AtEnd keeps cribs at the end of the message, like signatures.
+func AtEnd() Constraint {
+	return func(offset int, cribLength int, messageLength int) bool {
+		return offset+cribLength == messageLength
+	}
+}
+
+// Within keeps cribs starting between the offsets, both included.
+func Within(first int, last int) Constraint {
+	return func(offset int, _ int, _ int) bool {
+		return offset >= first && offset <= last
+	}
+}
+
+// FindCribs slides the crib along the message and returns every placement without a clash, the best menus first. The
+// constraint filters the offsets, nil keeps all.
+func FindCribs(crib string, message string, constraint Constraint) ([]Placement, error) {
+	return FindAllCribs([]string{crib}, message, constraint)
+}
+
+// FindAllCribs tries every crib like FindCribs and ranks all placements together: more loops first, then longer
+// cribs, then earlier offsets.
+func FindAllCribs(cribs []string, message string, constraint Constraint) ([]Placement, error) {
+	letters, lettersError := defs.Letters(message)
+	if lettersError != nil {
+		return nil, fmt.Errorf("invalid message: %v", lettersError)
+	}
+
+	var placements []Placement
+	for _, crib := range cribs {
+		cribLetters, cribError := defs.Letters(crib)
+		if cribError != nil {
+			return nil, fmt.Errorf("invalid crib %q: %v", crib, cribError)
+		}
+
+		if len(cribLetters) == 0 {
+			return nil, fmt.Errorf("invalid crib %q, expected letters", crib)
+		}
+
+		for offset := 0; offset+len(cribLetters) <= len(letters); offset++ {
+			if constraint != nil && !constraint(offset, len(cribLetters), len(letters)) {
+				continue
+			}
+
+			if clashes(cribLetters, letters[offset:]) {
+				continue
+			}
+
+			// a long crib is placed as a whole, the menu takes its start
+			menu, menuError := NewMenu(cribLetters[:min(len(cribLetters), MaxCribLength)], letters, offset)
+			if menuError != nil {
+				return nil, menuError
+			}
+
+			placements = append(placements, Placement{Crib: cribLetters, Offset: offset, Menu: menu})
+		}
+	}
+
+	slices.SortStableFunc(placements, func(one Placement, two Placement) int {
+		switch {
+		case one.Menu.Loops() != two.Menu.Loops():
+			return two.Menu.Loops() - one.Menu.Loops()
+
+		case len(one.Crib) != len(two.Crib):
+			return len(two.Crib) - len(one.Crib)
+		}
+
+		return one.Offset - two.Offset
+	})
+
+	return placements, nil
+}
+
+func (what Placement) String() string {
+	return fmt.Sprintf("%v at %d, %d loops", what.Crib, what.Offset, what.Menu.Loops())
+}
+
+// clashes reports whether a letter of the crib meets itself in the cipher text.
+func clashes(crib string, cipherText string) bool {
+	for index := range crib {
+		if crib[index] == cipherText[index] {
+			return true
+		}
+	}
+
+	return false
+}
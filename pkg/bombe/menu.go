@@ -0,0 +1,211 @@
+// Package bombe simulates the known-plaintext attack of the Turing-Welchman Bombe: a crib slid along the cipher text
+// to where it puts no letter onto itself, the menu of the crib at that place, and the Bombe trying every rotor order
+// and start position against the menu.
+package bombe
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"strings"
+)
+
+// MaxCribLength is the longest crib of a menu. The Bombe only turns the right rotor, a crib of 26 letters or more
+// always spans a turnover of the middle rotor.
+const MaxCribLength = 25
+
+// Link is a connection of the menu: at the position of the crib the machine turned the plain letter into the cipher
+// letter, and back.
+type Link struct {
+	Position int // letter of the crib, from 0
+	Plain    int
+	Cipher   int
+}
+
+// Menu is the letter graph of a crib lined up against the cipher text. The letters are the nodes, every position of
+// the crib links two of them, and loops in the graph are what makes wrong positions contradict themselves.
+type Menu struct {
+	Crib       string // plain text guessed for the cipher text
+	CipherText string // cipher text under the crib
+	Offset     int    // position of the crib in the message
+	Links      []Link
+	Centre     int // Testregister, the letter with the most links in the part of the graph with the most loops
+}
+
+// NewMenu lines the crib up against the message at the offset and builds the menu. Spaces are ignored, the crib must
+// not put a letter onto itself, which the machine never does.
+func NewMenu(crib string, message string, offset int) (*Menu, error) {
+	crib = strings.ToUpper(strings.Join(strings.Fields(crib), ""))
+	message = strings.ToUpper(strings.Join(strings.Fields(message), ""))
+
+	if len(crib) == 0 || len(crib) > MaxCribLength {
+		return nil, fmt.Errorf("invalid crib length %d, expected 1-%d", len(crib), MaxCribLength)
+	}
+
+	if offset < 0 || offset+len(crib) > len(message) {
+		return nil, fmt.Errorf("crib of %d letters does not fit into the message of %d letters at offset %d", len(crib),
+			len(message), offset)
+	}
+
+	menu := &Menu{
+		Crib:       crib,
+		CipherText: message[offset : offset+len(crib)],
+		Offset:     offset,
+	}
+
+	for position := range crib {
+		plain := strings.IndexByte(defs.UpperCase, crib[position])
+		cipher := strings.IndexByte(defs.UpperCase, menu.CipherText[position])
+		if plain < 0 || cipher < 0 {
+			return nil, fmt.Errorf("invalid letter at crib position %d", position)
+		}
+
+		if plain == cipher {
+			return nil, fmt.Errorf("crib puts %c onto itself at position %d", crib[position], position)
+		}
+
+		menu.Links = append(menu.Links, Link{Position: position, Plain: plain, Cipher: cipher})
+	}
+
+	menu.Centre = menu.centre()
+
+	return menu, nil
+}
+
+// Letters returns the letters of the menu in alphabetical order.
+func (what *Menu) Letters() []int {
+	var used [26]bool
+	for _, link := range what.Links {
+		used[link.Plain] = true
+		used[link.Cipher] = true
+	}
+
+	var letters []int
+	for letter, isUsed := range used {
+		if isUsed {
+			letters = append(letters, letter)
+		}
+	}
+
+	return letters
+}
+
+// Components returns the connected parts of the menu, each as its letters.
+func (what *Menu) Components() [][]int {
+	component := what.components()
+
+	var components [][]int
+	for _, letter := range what.Letters() {
+		for len(components) <= component[letter] {
+			components = append(components, nil)
+		}
+
+		components[component[letter]] = append(components[component[letter]], letter)
+	}
+
+	return components
+}
+
+// Loops returns the number of independent loops, the more the fewer false stops.
+func (what *Menu) Loops() int {
+	return len(what.Links) - len(what.Letters()) + len(what.Components())
+}
+
+func (what *Menu) String() string {
+	var links []string
+	for _, link := range what.Links {
+		links = append(links, fmt.Sprintf("%d:%c%c", link.Position+1, defs.UpperCase[link.Plain], defs.UpperCase[link.Cipher]))
+	}
+
+	return fmt.Sprintf("%v (centre %c, %d loops)", strings.Join(links, " "), defs.UpperCase[what.Centre], what.Loops())
+}
+
+// components numbers the connected parts in the order of their first letter, -1 for letters not on the menu.
+func (what *Menu) components() [26]int {
+	var component [26]int
+	for letter := range component {
+		component[letter] = -1
+	}
+
+	count := 0
+	for _, start := range what.Letters() {
+		if component[start] != -1 {
+			continue
+		}
+
+		component[start] = count
+		queue := []int{start}
+		for len(queue) > 0 {
+			letter := queue[0]
+			queue = queue[1:]
+			for _, link := range what.Links {
+				for _, next := range what.neighbours(link, letter) {
+					if component[next] == -1 {
+						component[next] = count
+						queue = append(queue, next)
+					}
+				}
+			}
+		}
+
+		count++
+	}
+
+	return component
+}
+
+// neighbours returns the other end of the link if it touches the letter.
+func (what *Menu) neighbours(link Link, letter int) []int {
+	switch letter {
+	case link.Plain:
+		return []int{link.Cipher}
+
+	case link.Cipher:
+		return []int{link.Plain}
+	}
+
+	return nil
+}
+
+// centre picks the letter with the most links in the part with the most loops, ties go to the larger part.
+func (what *Menu) centre() int {
+	component := what.components()
+
+	var links, letters [26]int
+	var linksPerLetter [26]int
+	for _, link := range what.Links {
+		links[component[link.Plain]]++
+		linksPerLetter[link.Plain]++
+		linksPerLetter[link.Cipher]++
+	}
+
+	for _, letter := range what.Letters() {
+		letters[component[letter]]++
+	}
+
+	best := -1
+	for _, letter := range what.Letters() {
+		if best == -1 {
+			best = letter
+			continue
+		}
+
+		part, bestPart := component[letter], component[best]
+		loops, bestLoops := links[part]-letters[part]+1, links[bestPart]-letters[bestPart]+1
+		switch {
+		case loops != bestLoops:
+			if loops > bestLoops {
+				best = letter
+			}
+
+		case letters[part] != letters[bestPart]:
+			if letters[part] > letters[bestPart] {
+				best = letter
+			}
+
+		case linksPerLetter[letter] > linksPerLetter[best]:
+			best = letter
+		}
+	}
+
+	return best
+}
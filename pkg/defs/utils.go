@@ -2,8 +2,10 @@ package defs
 
 import (
 	crand "crypto/rand"
+	"fmt"
 	"math/big"
 	mrand "math/rand"
+	"strings"
 )
 
 func RandomInt(min int, max int) int {
@@ -20,3 +22,15 @@ func RandomInt(min int, max int) int {
 	bigNumber.Add(bigNumber, big.NewInt(int64(min)))
 	return int(bigNumber.Int64())
 }
+
+// Letters drops the whitespace of the text and returns it in upper case, any other character but a letter is invalid.
+func Letters(text string) (string, error) {
+	letters := strings.ToUpper(strings.Join(strings.Fields(text), ""))
+	for index := range letters {
+		if strings.IndexByte(UpperCase, letters[index]) < 0 {
+			return "", fmt.Errorf("invalid character %q at %d", letters[index], index)
+		}
+	}
+
+	return letters, nil
+}
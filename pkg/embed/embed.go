@@ -10,3 +10,21 @@ var RotorsYaml []byte
 
 //go:embed config/reflectors.yaml
 var ReflectorsYaml []byte
+
+//go:embed config/uhr.yaml
+var UhrYaml []byte
+
+//go:embed config/entry-wheels.yaml
+var EntryWheelsYaml []byte
+
+//go:embed config/machines.yaml
+var MachinesYaml []byte
+
+//go:embed config/settings-oct_1944.txt
+var SettingsOct1944Txt []byte
+
+//go:embed config/ngrams-german.txt
+var NGramsGermanTxt []byte
+
+//go:embed config/ngrams-english.txt
+var NGramsEnglishTxt []byte
@@ -3,13 +3,17 @@ package enigma
 import (
 	_ "embed"
 	"fmt"
-	"github.com/r3db34n1an/enigma/pkg/defs"
 	"github.com/r3db34n1an/enigma/pkg/settings"
 	"regexp"
+	"slices"
 	"strings"
-	"unicode"
+	"sync"
 )
 
+// maxScramblerBytes limits the memory of the precomputed scramblers kept. One takes 26 bytes for every rotor position,
+// about 450 KB for three rotors and 11.9 MB with a fourth rotor or a settable reflector.
+const maxScramblerBytes = 64 << 20
+
 // Sources:
 //	- https://blog.gopheracademy.com/advent-2016/enigma-emulator-in-go/
 //	- https://www.ciphermachinesandcryptology.com/en/enigmaproc.htm
@@ -20,7 +24,13 @@ import (
 type Enigma struct {
 	preserveFormatting bool
 	preserveCase       bool
-	setting            settings.Setting
+	registry           *settings.Registry
+	precomputed        bool
+	tracer             Tracer
+	mutex              sync.Mutex
+	scramblers         map[string]*settings.Scrambler
+	recentScramblers   []string // least recently used first
+	scramblerBytes     int
 }
 
 func NewEnigma(preserveFormatting bool, preserveCase bool) (*Enigma, error) {
@@ -34,82 +44,32 @@ func (what *Enigma) Init() error {
 	return nil
 }
 
-func (what *Enigma) Encrypt(plainText []byte, key string) ([]byte, error) {
-	return what.EncryptWithPlugBoard(plainText, key, "")
+// SetRegistry makes keys resolve rotors, reflectors and machines through the given registry, set it before sharing the
+// engine between goroutines.
+func (what *Enigma) SetRegistry(registry *settings.Registry) {
+	what.registry = registry
 }
 
-func (what *Enigma) EncryptWithSetting(plainText []byte, setting *settings.Setting) ([]byte, error) {
-	*what = Enigma{
-		preserveFormatting: what.preserveFormatting,
-		preserveCase:       what.preserveCase,
-	}
-
-	if setting == nil {
-		return nil, fmt.Errorf("no setting")
-	}
-
-	what.setting = *setting
-	var cipherText []byte
-	for index, plain := range plainText {
-		if what.preserveFormatting {
-			if !what.shouldEncrypt(plain) {
-				cipherText = append(cipherText, plain)
-				continue
-			}
-		} else {
-			if index > 0 {
-				if index%5 == 0 {
-					cipherText = append(cipherText, ' ')
-				}
-
-				if index%80 == 0 {
-					cipherText = append(cipherText, '\n')
-				}
-			}
-		}
-
-		what.setting.Rotors.Move()
-
-		plainRune := rune(plain)
-		encrypted := strings.IndexRune(defs.UpperCase, unicode.ToUpper(plainRune))
-		if encrypted < 0 {
-			return nil, fmt.Errorf("invalid character %q", plain)
-		}
-
-		encrypted = what.setting.PlugBoard.Transform(encrypted)
-		if encrypted < 0 || encrypted > len(defs.UpperCase) {
-			return nil, fmt.Errorf("plug board encryption of %q failed", plain)
-		}
-
-		encrypted = what.setting.Rotors.Encrypt(encrypted)
-		if encrypted < 0 || encrypted > len(defs.UpperCase) {
-			return nil, fmt.Errorf("plug rotor encryption of %q failed", plain)
-		}
-
-		encrypted = what.setting.Reflector.Reflect(encrypted)
-		if encrypted < 0 || encrypted > len(defs.UpperCase) {
-			return nil, fmt.Errorf("plug reflection of %q failed", plain)
-		}
-
-		encrypted = what.setting.Rotors.Decrypt(encrypted)
-		if encrypted < 0 || encrypted > len(defs.UpperCase) {
-			return nil, fmt.Errorf("plug rotor decryption of %q failed", plain)
-		}
-
-		encrypted = what.setting.PlugBoard.Transform(encrypted)
-		if encrypted < 0 || encrypted >= len(defs.UpperCase) {
-			return nil, fmt.Errorf("plug board decryption of %q failed", plain)
-		}
+// SetPrecomputed makes the engine precompute the scrambler permutation for every rotor position of a wheel order and
+// reuse it for later texts with the same wheel order. This pays off for long texts and many trial decryptions.
+func (what *Enigma) SetPrecomputed(precomputed bool) {
+	what.precomputed = precomputed
+}
 
-		encryptedRune := rune(defs.UpperCase[encrypted])
-		if unicode.IsLower(plainRune) && what.preserveCase {
-			encryptedRune = unicode.ToLower(encryptedRune)
-		}
+// SetTracer makes the engine report the path of every letter to the tracer, nil turns tracing off. Traced letters
+// take the slow path through the components, the precomputed scramblers are not used.
+func (what *Enigma) SetTracer(tracer Tracer) {
+	what.tracer = tracer
+}
 
-		cipherText = append(cipherText, byte(encryptedRune))
-	}
+// Encrypt runs the text through the machine set up by the key. Unless the formatting is preserved, the text may only
+// hold letters, anything else is an invalid character, and the output is grouped in fives and lines of 80 letters.
+func (what *Enigma) Encrypt(plainText []byte, key string) ([]byte, error) {
+	return what.EncryptWithPlugBoard(plainText, key, "")
+}
 
-	return cipherText, nil
+func (what *Enigma) EncryptWithSetting(plainText []byte, setting *settings.Setting) ([]byte, error) {
+	return what.process(plainText, setting)
 }
 
 func (what *Enigma) EncryptWithPlugBoard(plainText []byte, key string, plugBoard string) ([]byte, error) {
@@ -126,77 +86,7 @@ func (what *Enigma) Decrypt(cipherText []byte, key string) ([]byte, error) {
 }
 
 func (what *Enigma) DecryptWithSetting(cipherText []byte, setting *settings.Setting) ([]byte, error) {
-	*what = Enigma{
-		preserveFormatting: what.preserveFormatting,
-		preserveCase:       what.preserveCase,
-	}
-
-	if setting == nil {
-		return nil, fmt.Errorf("no setting")
-	}
-
-	what.setting = *setting
-	var plainText []byte
-	for index, encrypted := range cipherText {
-		if what.preserveFormatting {
-			if !what.shouldEncrypt(encrypted) {
-				plainText = append(plainText, encrypted)
-				continue
-			}
-		} else {
-			if index > 0 {
-				if index%5 == 0 {
-					plainText = append(plainText, ' ')
-				}
-
-				if index%80 == 0 {
-					plainText = append(plainText, '\n')
-				}
-			}
-		}
-
-		what.setting.Rotors.Move()
-
-		encryptedRune := rune(encrypted)
-		plain := strings.IndexRune(defs.UpperCase, unicode.ToUpper(encryptedRune))
-		if plain < 0 {
-			return nil, fmt.Errorf("invalid character %q", encrypted)
-		}
-
-		plain = what.setting.PlugBoard.Transform(plain)
-		if plain < 0 || plain > len(defs.UpperCase) {
-			return nil, fmt.Errorf("plug board encryption of %q failed", encrypted)
-		}
-
-		plain = what.setting.Rotors.Encrypt(plain)
-		if plain < 0 || plain > len(defs.UpperCase) {
-			return nil, fmt.Errorf("plug rotor encryption of %q failed", encrypted)
-		}
-
-		plain = what.setting.Reflector.Reflect(plain)
-		if plain < 0 || plain > len(defs.UpperCase) {
-			return nil, fmt.Errorf("plug reflection of %q failed", encrypted)
-		}
-
-		plain = what.setting.Rotors.Decrypt(plain)
-		if plain < 0 || plain > len(defs.UpperCase) {
-			return nil, fmt.Errorf("plug rotor decryption of %q failed", encrypted)
-		}
-
-		plain = what.setting.PlugBoard.Transform(plain)
-		if plain < 0 || plain >= len(defs.UpperCase) {
-			return nil, fmt.Errorf("plug board decryption of %q failed", encrypted)
-		}
-
-		plainRune := rune(defs.UpperCase[plain])
-		if unicode.IsLower(encryptedRune) && what.preserveCase {
-			plainRune = unicode.ToLower(plainRune)
-		}
-
-		plainText = append(plainText, byte(plainRune))
-	}
-
-	return plainText, nil
+	return what.process(cipherText, setting)
 }
 
 func (what *Enigma) DecryptWithPlugBoard(cipherText []byte, key string, plugBoard string) ([]byte, error) {
@@ -250,7 +140,9 @@ func (what *Enigma) Sanitize(plainText string) string {
 }
 
 func (what *Enigma) readKeyAndPlugBoard(key string, plugBoard string) (*settings.Setting, error) {
-	setting := new(settings.Setting)
+	setting := &settings.Setting{
+		Registry: what.registry,
+	}
 
 	var importedKey settings.ExportSetting
 	parseError := importedKey.Parse(key)
@@ -258,25 +150,104 @@ func (what *Enigma) readKeyAndPlugBoard(key string, plugBoard string) (*settings
 		return nil, fmt.Errorf("failed to parse key: %v", parseError)
 	}
 
+	if len(plugBoard) > 0 {
+		if importedKey.Uhr != nil {
+			importedKey.Uhr.Plugs = plugBoard
+		} else {
+			var parsedPlugBoard settings.PlugBoard
+			plugBoardError := parsedPlugBoard.Parse(plugBoard)
+			if plugBoardError != nil {
+				return nil, fmt.Errorf("failed to load plug board: %v", plugBoardError)
+			}
+
+			importedKey.PlugBoard = parsedPlugBoard.Export()
+		}
+	}
+
 	importError := setting.Import(importedKey)
 	if importError != nil {
 		return nil, fmt.Errorf("failed to import key: %v", importError)
 	}
 
-	if len(plugBoard) > 0 {
-		plugBoardError := setting.LoadPlugBoard(plugBoard)
-		if plugBoardError != nil {
-			return nil, fmt.Errorf("failed to load plug board: %v", plugBoardError)
-		}
+	return setting, nil
+}
+
+func (what *Enigma) scrambler(setting *settings.Setting) (*settings.Scrambler, error) {
+	key := settings.ScramblerKey(setting)
+	cached := what.cachedScrambler(key)
+	if cached != nil {
+		return cached, nil
 	}
 
-	return setting, nil
+	// built without the lock, concurrent texts with the same wheel order may both build it
+	scrambler, scramblerError := settings.NewScrambler(setting)
+	if scramblerError != nil {
+		return nil, fmt.Errorf("failed to precompute scrambler: %v", scramblerError)
+	}
+
+	what.mutex.Lock()
+	defer what.mutex.Unlock()
+
+	cached, ok := what.scramblers[key]
+	if ok {
+		return cached, nil
+	}
+
+	// the least recently used scramblers make room
+	for len(what.recentScramblers) > 0 && what.scramblerBytes+scrambler.Size() > maxScramblerBytes {
+		oldest := what.recentScramblers[0]
+		what.recentScramblers = what.recentScramblers[1:]
+		what.scramblerBytes -= what.scramblers[oldest].Size()
+		delete(what.scramblers, oldest)
+	}
+
+	if scrambler.Size() > maxScramblerBytes {
+		return scrambler, nil
+	}
+
+	if what.scramblers == nil {
+		what.scramblers = make(map[string]*settings.Scrambler)
+	}
+
+	what.scramblers[key] = scrambler
+	what.recentScramblers = append(what.recentScramblers, key)
+	what.scramblerBytes += scrambler.Size()
+
+	return scrambler, nil
 }
 
-func (what *Enigma) shouldEncrypt(c byte) bool {
-	if what.preserveCase {
-		return strings.ContainsRune(defs.UpperCase, unicode.ToUpper(rune(c)))
+// cachedScrambler returns the kept scrambler for the key and marks it as recently used, or nil.
+func (what *Enigma) cachedScrambler(key string) *settings.Scrambler {
+	what.mutex.Lock()
+	defer what.mutex.Unlock()
+
+	cached, ok := what.scramblers[key]
+	if !ok {
+		return nil
+	}
+
+	index := slices.Index(what.recentScramblers, key)
+	what.recentScramblers = append(slices.Delete(what.recentScramblers, index, index+1), key)
+
+	return cached
+}
+
+// process runs the text through the machine, encryption and decryption are the same operation. It works on a copy of
+// the setting, so neither the engine nor the caller's setting changes and calls may run concurrently.
+func (what *Enigma) process(in []byte, setting *settings.Setting) ([]byte, error) {
+	transformer, transformerError := what.NewTransformer(setting)
+	if transformerError != nil {
+		return nil, transformerError
+	}
+
+	out := make([]byte, 0, len(in)+len(in)/4)
+	for _, inByte := range in {
+		var appendError error
+		out, appendError = transformer.appendByte(out, inByte)
+		if appendError != nil {
+			return nil, appendError
+		}
 	}
 
-	return strings.ContainsRune(defs.UpperCase, rune(c))
+	return out, nil
 }
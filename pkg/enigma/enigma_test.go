@@ -1,8 +1,22 @@
 package enigma
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/embed"
+	"github.com/r3db34n1an/enigma/pkg/settings"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/transform"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 var testCases = []TestCase{
@@ -81,6 +95,32 @@ rotors:
       position: C
       ring_setting: A
 reflector: B
+plug_board:
+    A: B
+    C: D
+    E: F
+`,
+		Plain:              "HELLOWORLD",
+		Encrypted:          "YGMGTTPJNJ",
+		Decrypted:          "HELLOWORLD",
+		PreserveFormatting: true,
+	},
+	{
+		Key: `
+rotors:
+    - name: Beta
+      position: A
+      ring_setting: A
+    - name: III
+      position: A
+      ring_setting: A
+    - name: II
+      position: B
+      ring_setting: A
+    - name: IV
+      position: C
+      ring_setting: A
+reflector: B-Thin
 plug_board:
     A: B
     C: D
@@ -242,3 +282,1643 @@ func TestDecrypt(t *testing.T) {
 		assert.Equal(t, item.Decrypted, string(result))
 	}
 }
+
+func TestM4Compatibility(t *testing.T) {
+	plain := []byte("DIESXISTXEINXTESTXDERXMARINEXMITXVIERXWALZEN")
+	wide := `
+rotors:
+    - name: I
+      position: Q
+      ring_setting: C
+    - name: VI
+      position: E
+      ring_setting: K
+    - name: VIII
+      position: V
+      ring_setting: T
+reflector: C
+`
+	thin := `
+rotors:
+    - name: Gamma
+      position: A
+      ring_setting: A
+    - name: I
+      position: Q
+      ring_setting: C
+    - name: VI
+      position: E
+      ring_setting: K
+    - name: VIII
+      position: V
+      ring_setting: T
+reflector: C-Dünn
+`
+
+	cipher, cipherError := NewEnigma(true, false)
+	assert.Nil(t, cipherError)
+
+	expected, wideError := cipher.Encrypt(plain, wide)
+	assert.Nil(t, wideError)
+
+	result, thinError := cipher.Encrypt(plain, thin)
+	assert.Nil(t, thinError)
+	assert.Equal(t, string(expected), string(result))
+}
+
+// TestM4Message decrypts the signal of U-264 (Kapitänleutnant Looks) of 25 November 1942, which stayed unbroken until
+// the M4 project found the key in 2006: Greek rotor Beta, thin reflector B and the ring setting of the rightmost rotor.
+func TestM4Message(t *testing.T) {
+	key := "{rotors: [{name: Beta, position: V}, {name: II, position: J}, {name: IV, position: N}, {name: I, position: A, ring_setting: V}], reflector: B-Dünn}"
+	plugBoard := "AT BL DF GJ HM NW OP QY RZ VX"
+	cipherText := "NCZWVUSXPNYMINHZXMQXSFWXWLKJAHSHNMCOCCAKUQPMKCSMHKSEINJUSBLKIOSXCKUBHMLLXCSJUSRRDVKOHULXWCCBGVLIYXEOAHXRHKKFVDREWEZLXOBAFGYUJQUKGRTVUKAMEURBVEKSUHHVOYHABCJWMAKLFKLMYFVNRIZRVVRTKOFDANJMOLBGFFLEOPRGTFLVRHOWOPBEKVWMUQFMPWPARMFHAGKXIIBG"
+	plainText := "VONVONJLOOKSJHFFTTTEINSEINSDREIZWOYYQNNSNEUNINHALTXXBEIANGRIFFUNTERWASSERGEDRUECKTYWABOSXLETZTERGEGNERSTANDNULACHTDREINULUHRMARQUANTONJOTANEUNACHTSEYHSDREIYZWOZWONULGRADYACHTSMYSTOSSENACHXEKNSVIERMBFAELLTYNNNNNNOOOVIERYSICHTEINSNULL"
+
+	cipher, cipherError := NewEnigma(true, false)
+	assert.Nil(t, cipherError)
+
+	result, decryptError := cipher.DecryptWithPlugBoard([]byte(cipherText), key, plugBoard)
+	assert.Nil(t, decryptError)
+	assert.Equal(t, plainText, string(result))
+
+	result, encryptError := cipher.EncryptWithPlugBoard([]byte(plainText), key, plugBoard)
+	assert.Nil(t, encryptError)
+	assert.Equal(t, cipherText, string(result))
+}
+
+func TestM4Validation(t *testing.T) {
+	invalidKeys := []string{
+		// greek rotor outside the leftmost slot
+		`
+rotors:
+    - name: I
+      position: A
+      ring_setting: A
+    - name: Beta
+      position: A
+      ring_setting: A
+    - name: II
+      position: A
+      ring_setting: A
+    - name: III
+      position: A
+      ring_setting: A
+reflector: B-Thin
+`,
+		// wide reflector with 4 rotors
+		`
+rotors:
+    - name: Beta
+      position: A
+      ring_setting: A
+    - name: I
+      position: A
+      ring_setting: A
+    - name: II
+      position: A
+      ring_setting: A
+    - name: III
+      position: A
+      ring_setting: A
+reflector: B
+`,
+		// thin reflector with 3 rotors
+		`
+rotors:
+    - name: I
+      position: A
+      ring_setting: A
+    - name: II
+      position: A
+      ring_setting: A
+    - name: III
+      position: A
+      ring_setting: A
+reflector: B-Thin
+`,
+	}
+
+	cipher, cipherError := NewEnigma(true, false)
+	assert.Nil(t, cipherError)
+
+	for _, key := range invalidKeys {
+		_, encryptError := cipher.Encrypt([]byte("TEST"), key)
+		assert.NotNil(t, encryptError)
+	}
+}
+
+func TestRewirableReflector(t *testing.T) {
+	plain := []byte("FLIEGERKORPSXMELDETXANGRIFFXAUFXDENXHAFEN")
+	keyFormat := `
+rotors:
+    - name: I
+      position: M
+      ring_setting: B
+    - name: IV
+      position: Q
+      ring_setting: X
+    - name: III
+      position: D
+      ring_setting: K
+reflector: D
+reflector_wiring: %v
+reflector_notation: %v
+plug_board:
+    A: H
+    K: Z
+`
+
+	cipher, cipherError := NewEnigma(true, false)
+	assert.Nil(t, cipherError)
+
+	bletchley, bletchleyError := cipher.Encrypt(plain, fmt.Sprintf(keyFormat, "AC DE FG HI JK LM NP QR ST UV WX YZ", "bletchley"))
+	assert.Nil(t, bletchleyError)
+	assert.NotEqual(t, string(plain), string(bletchley))
+
+	german, germanError := cipher.Encrypt(plain, fmt.Sprintf(keyFormat, "AZ XW VU TS RQ PO NM LK IH GF ED CB", "german"))
+	assert.Nil(t, germanError)
+	assert.Equal(t, string(bletchley), string(german))
+
+	decrypted, decryptError := cipher.Decrypt(german, fmt.Sprintf(keyFormat, "AC DE FG HI JK LM NP QR ST UV WX YZ", "bletchley"))
+	assert.Nil(t, decryptError)
+	assert.Equal(t, string(plain), string(decrypted))
+
+	invalidWirings := []string{
+		"AC DE FG HI JK LM NP QR ST UV WX",    // too few pairs
+		"AB DE FG HI JK LM NP QR ST UV WX YZ", // uses the fixed pair
+		"AC AE FG HI JK LM NP QR ST UV WX YZ", // duplicate letter
+		"AA DE FG HI JK LM NP QR ST UV WX YZ", // fixed point
+	}
+
+	for _, wiring := range invalidWirings {
+		_, encryptError := cipher.Encrypt(plain, fmt.Sprintf(keyFormat, wiring, "bletchley"))
+		assert.NotNil(t, encryptError)
+	}
+
+	_, missingError := cipher.Encrypt(plain, `
+rotors:
+    - name: I
+      position: A
+      ring_setting: A
+    - name: II
+      position: A
+      ring_setting: A
+    - name: III
+      position: A
+      ring_setting: A
+reflector: D
+`)
+	assert.NotNil(t, missingError)
+}
+
+func TestUhr(t *testing.T) {
+	plain := []byte("KEINEXBESONDERENXEREIGNISSEXWETTERXKLAR")
+	keyFormat := `
+rotors:
+    - name: II
+      position: K
+      ring_setting: F
+    - name: V
+      position: R
+      ring_setting: O
+    - name: I
+      position: W
+      ring_setting: P
+reflector: B
+%v
+`
+	plugs := "KL IT FQ HY XC NP VZ JB SE OG"
+
+	cipher, cipherError := NewEnigma(true, false)
+	assert.Nil(t, cipherError)
+
+	// at position 00 the uhr behaves like the plain plug board
+	plugBoard, plugBoardError := cipher.EncryptWithPlugBoard(plain, fmt.Sprintf(keyFormat, ""), plugs)
+	assert.Nil(t, plugBoardError)
+
+	uhrZero, uhrZeroError := cipher.Encrypt(plain, fmt.Sprintf(keyFormat, "uhr:\n    position: 0\n    plugs: "+plugs))
+	assert.Nil(t, uhrZeroError)
+	assert.Equal(t, string(plugBoard), string(uhrZero))
+
+	// known answer at position 27, traced pin by pin through the uhr wiring by a separate model
+	uhrKey := fmt.Sprintf(keyFormat, "uhr:\n    position: 27\n    plugs: "+plugs)
+	uhrKnown, uhrKnownError := cipher.Encrypt(plain, uhrKey)
+	assert.Nil(t, uhrKnownError)
+	assert.Equal(t, "NQYSAVFOKJKFRYSAORHVWLMADCXHGHDCYASOEHX", string(uhrKnown))
+
+	for position := 1; position < 40; position++ {
+		key := fmt.Sprintf(keyFormat, fmt.Sprintf("uhr:\n    position: %d\n    plugs: %v", position, plugs))
+
+		encrypted, encryptError := cipher.Encrypt(plain, key)
+		assert.Nil(t, encryptError)
+		assert.NotEqual(t, string(plugBoard), string(encrypted))
+
+		decrypted, decryptError := cipher.Decrypt(encrypted, key)
+		assert.Nil(t, decryptError)
+		assert.Equal(t, string(plain), string(decrypted))
+	}
+
+	invalidUhrs := []string{
+		"uhr:\n    position: 40\n    plugs: " + plugs,
+		"uhr:\n    position: 12\n    plugs: KL IT FQ HY XC NP VZ JB SE",
+	}
+
+	for _, invalidUhr := range invalidUhrs {
+		_, encryptError := cipher.Encrypt(plain, fmt.Sprintf(keyFormat, invalidUhr))
+		assert.NotNil(t, encryptError)
+	}
+}
+
+func TestCommercial(t *testing.T) {
+	plain := []byte("ANKUNFTXDESXAGENTENXMORGENXFRUEH")
+	keys := []string{
+		// Enigma D
+		`
+machine: D
+rotors:
+    - name: I-D
+      position: C
+      ring_setting: M
+    - name: II-D
+      position: E
+      ring_setting: A
+    - name: III-D
+      position: X
+      ring_setting: R
+reflector: K
+reflector_position: F
+reflector_ring_setting: B
+`,
+		// Swiss-K
+		`
+machine: K
+rotors:
+    - name: III-K
+      position: "Y"
+      ring_setting: B
+    - name: I-K
+      position: D
+      ring_setting: "N"
+    - name: II-K
+      position: E
+      ring_setting: Q
+reflector: K
+reflector_position: S
+reflector_ring_setting: A
+`,
+		// Abwehr G-312
+		`
+machine: G
+rotors:
+    - name: II-G
+      position: S
+      ring_setting: J
+    - name: I-G
+      position: "N"
+      ring_setting: A
+    - name: III-G
+      position: U
+      ring_setting: K
+reflector: G
+reflector_position: W
+reflector_ring_setting: C
+`,
+		// Enigma T
+		`
+machine: T
+rotors:
+    - name: VII-T
+      position: K
+      ring_setting: O
+    - name: II-T
+      position: R
+      ring_setting: A
+    - name: V-T
+      position: W
+      ring_setting: L
+reflector: T
+reflector_position: M
+reflector_ring_setting: A
+`,
+		// Railway Enigma
+		`
+machine: Railway
+rotors:
+    - name: II-R
+      position: B
+      ring_setting: U
+    - name: III-R
+      position: X
+      ring_setting: D
+    - name: I-R
+      position: "N"
+      ring_setting: H
+reflector: R
+reflector_position: J
+reflector_ring_setting: A
+`,
+	}
+
+	cipher, cipherError := NewEnigma(true, false)
+	assert.Nil(t, cipherError)
+
+	for _, key := range keys {
+		encrypted, encryptError := cipher.Encrypt(plain, key)
+		assert.Nil(t, encryptError)
+		assert.NotEqual(t, string(plain), string(encrypted))
+
+		decrypted, decryptError := cipher.Decrypt(encrypted, key)
+		assert.Nil(t, decryptError)
+		assert.Equal(t, string(plain), string(decrypted))
+	}
+
+	// known answers over several turnovers, computed with a separate implementation typed from the published wiring
+	// tables rather than from rotors.yaml, so a wrong wiring, notch or entry wheel fails
+	long := []byte("ANKUNFTXDESXAGENTENXMORGENXFRUEHXTREFFPUNKTXBAHNHOFXZUERICHXUHRZEITXNEUNXUHRXDREISSIG")
+	for index, expected := range []string{
+		"UFHZKAIJERVWIRVPFCXEIQEFNLEAKHVXALVSRIRQBBVSXEKPQHHQEHJTETUBQKZDMFNMTSZGMYRWQXHSRZHCP",
+		"SWJKJKAESYJRSAGGZZFLZYYUQBHGMYKNYPIMQHDILBGCRSDGWMVCKWQUXYKOOVKQAYQNHNMQLLAKCWWTHMRJB",
+		"FGEAZIBEKTJULASKHWCBETATVCPBYHJXEBIACCAARQEOCLUOYQXINSQQTUTQKMSIJGPDFRDQFCQQIHPXGIWXK",
+		"UIYLQIDLCFDSZVBVVHMTHSASRDKNMCGVYDLZRQUHRFDNAYFESTRMMSWDSSIEQUUQIREHGNQVZFQPTUOLQJAKJ",
+		"ZSSAAWXLGTQELZRUAGXKIJZPNIAVWAOPLSWQVBWFOPSQQKSEFACAJIFUTQWBFKUGPEJZHMRIERUVFKIOTGWQU",
+	} {
+		encrypted, encryptError := cipher.Encrypt(long, keys[index])
+		assert.Nil(t, encryptError)
+		assert.Equal(t, expected, strings.Join(strings.Fields(string(encrypted)), ""), keys[index])
+	}
+
+	_, plugBoardError := cipher.EncryptWithPlugBoard(plain, keys[1], "AB CD")
+	assert.NotNil(t, plugBoardError)
+
+	_, settableError := cipher.Encrypt(plain, `
+rotors:
+    - name: I
+      position: A
+      ring_setting: A
+    - name: II
+      position: A
+      ring_setting: A
+    - name: III
+      position: A
+      ring_setting: A
+reflector: B
+reflector_position: C
+reflector_ring_setting: A
+`)
+	assert.NotNil(t, settableError)
+}
+
+func TestStepping(t *testing.T) {
+	positions := func(setting *settings.Setting) string {
+		result := string(rune('A' + setting.Reflector.Position))
+		for _, rotor := range setting.Rotors {
+			result += string(rune('A' + rotor.Position))
+		}
+
+		return result
+	}
+
+	// ratchet stepping with the double step of the middle rotor
+	var ratchet settings.ExportSetting
+	assert.Nil(t, ratchet.Parse(`
+rotors:
+    - name: I
+      position: A
+      ring_setting: A
+    - name: II
+      position: D
+      ring_setting: A
+    - name: III
+      position: U
+      ring_setting: A
+reflector: B
+`))
+
+	var ratchetSetting settings.Setting
+	assert.Nil(t, ratchetSetting.Import(ratchet))
+	for _, expected := range []string{"AADV", "AAEW", "ABFX", "ABFY"} {
+		ratchetSetting.Step()
+		assert.Equal(t, expected, positions(&ratchetSetting))
+	}
+
+	// gear stepping carries like an odometer, including the reflector
+	var gear settings.ExportSetting
+	assert.Nil(t, gear.Parse(`
+machine: G
+rotors:
+    - name: I-G
+      position: S
+      ring_setting: A
+    - name: II-G
+      position: S
+      ring_setting: A
+    - name: III-G
+      position: T
+      ring_setting: A
+reflector: G
+reflector_position: A
+reflector_ring_setting: A
+`))
+
+	var gearSetting settings.Setting
+	assert.Nil(t, gearSetting.Import(gear))
+	for _, expected := range []string{"ASSU", "BTTV", "BTTW", "BUUX", "BUVY", "BUVZ"} {
+		gearSetting.Step()
+		assert.Equal(t, expected, positions(&gearSetting))
+	}
+}
+
+func TestMachines(t *testing.T) {
+	keys := map[string]string{
+		"I": `
+rotors:
+    - name: I
+      position: A
+      ring_setting: A
+    - name: II
+      position: A
+      ring_setting: A
+    - name: III
+      position: A
+      ring_setting: A
+reflector: B
+`,
+		"M3": `
+rotors:
+    - name: VI
+      position: A
+      ring_setting: A
+    - name: II
+      position: A
+      ring_setting: A
+    - name: VIII
+      position: A
+      ring_setting: A
+reflector: C
+`,
+		"M4": `
+rotors:
+    - name: Beta
+      position: A
+      ring_setting: A
+    - name: VI
+      position: A
+      ring_setting: A
+    - name: II
+      position: A
+      ring_setting: A
+    - name: VIII
+      position: A
+      ring_setting: A
+reflector: B-Thin
+`,
+		"K": `
+rotors:
+    - name: I-K
+      position: A
+      ring_setting: A
+    - name: II-K
+      position: A
+      ring_setting: A
+    - name: III-K
+      position: A
+      ring_setting: A
+reflector: K
+`,
+	}
+
+	for machine, key := range keys {
+		var exported settings.ExportSetting
+		assert.Nil(t, exported.Parse(key))
+
+		var setting settings.Setting
+		assert.Nil(t, setting.Import(exported))
+		if assert.NotNil(t, setting.Machine, machine) {
+			assert.Equal(t, machine, setting.Machine.Name)
+		}
+	}
+
+	var wrongMachine settings.ExportSetting
+	assert.Nil(t, wrongMachine.Parse("machine: M3\n"+keys["K"]))
+
+	var setting settings.Setting
+	assert.NotNil(t, setting.Import(wrongMachine))
+}
+
+func TestRegistry(t *testing.T) {
+	key := `
+rotors:
+    - name: %s
+      position: A
+      ring_setting: A
+    - name: II
+      position: A
+      ring_setting: A
+    - name: III
+      position: A
+      ring_setting: A
+reflector: %s
+`
+	plainText := []byte("HELLOWORLD")
+
+	engine, engineError := NewEnigma(true, false)
+	assert.Nil(t, engineError)
+
+	expected, encryptError := engine.Encrypt(plainText, fmt.Sprintf(key, "I", "B"))
+	assert.Nil(t, encryptError)
+
+	// rotors and reflectors from a reader, usable in machine I
+	registry, registryError := settings.NewRegistry("")
+	assert.Nil(t, registryError)
+	assert.Nil(t, registry.LoadReader(strings.NewReader(`
+rotors:
+    X:
+        mapping: EKMFLGDQVZNTOWYHXUSPAIBRCJ
+        notches: [Q]
+        machines: [I]
+reflectors:
+    Y:
+        mapping: YRUHQSLDPXNGOKMIEBFZCWVJAT
+        machines: [I]
+`)))
+
+	engine.SetRegistry(registry)
+	cipherText, encryptError := engine.Encrypt(plainText, fmt.Sprintf(key, "X", "Y"))
+	assert.Nil(t, encryptError)
+	assert.Equal(t, expected, cipherText)
+
+	// the default registry does not know them
+	var exported settings.ExportSetting
+	assert.Nil(t, exported.Parse(fmt.Sprintf(key, "X", "Y")))
+
+	var setting settings.Setting
+	assert.NotNil(t, setting.Import(exported))
+
+	// key sheets naming them load with the registry
+	sheet := []byte("- day: 1\n  id_groups: [abc, def, ghi, jkl]\n  rotors: [X: 1, II: 1, III: 1]\n  reflector: Y\n")
+
+	var defaultSheet settings.Settings
+	assert.NotNil(t, defaultSheet.Load(sheet))
+
+	var registrySheet settings.Settings
+	assert.Nil(t, registrySheet.LoadWithRegistry(sheet, registry))
+	assert.Equal(t, registry, registrySheet[0].Registry)
+
+	sheetText, encryptError := engine.EncryptWithSetting(plainText, &registrySheet[0])
+	assert.Nil(t, encryptError)
+	assert.Equal(t, expected, sheetText)
+
+	// a rewirable reflector wired by the setting like UKW-D
+	assert.Nil(t, registry.AddReflector(settings.ReflectorDefinition{Name: "DX", Rewirable: true, Fixed: "bo", Machines: []string{"I"}}))
+	wiredKey := key + "reflector_wiring: AC DE FG HI JK LM NP QR ST UV WX YZ\n"
+
+	rewired, encryptError := engine.Encrypt(plainText, fmt.Sprintf(wiredKey, "IV", "DX"))
+	assert.Nil(t, encryptError)
+
+	engine.SetRegistry(nil)
+	builtIn, encryptError := engine.Encrypt(plainText, fmt.Sprintf(wiredKey, "IV", "D"))
+	assert.Nil(t, encryptError)
+	assert.Equal(t, builtIn, rewired)
+	engine.SetRegistry(registry)
+
+	// shadowing a built-in rotor
+	assert.Nil(t, registry.AddRotor(settings.RotorDefinition{
+		Name:    "I",
+		Mapping: "AJDKSIRUXBLHWTMCQGZNPYFVOE",
+		Notches: "E",
+	}))
+
+	shadowed, encryptError := engine.Encrypt(plainText, fmt.Sprintf(key, "I", "B"))
+	assert.Nil(t, encryptError)
+
+	engine.SetRegistry(nil)
+	original, encryptError := engine.Encrypt(plainText, fmt.Sprintf(key, "II", "B"))
+	assert.Nil(t, encryptError)
+	assert.Equal(t, original, shadowed)
+
+	// namespaced rotors keep the built-ins
+	namespaced, registryError := settings.NewRegistry("lib")
+	assert.Nil(t, registryError)
+	assert.Nil(t, namespaced.AddRotor(settings.RotorDefinition{
+		Name:     "I",
+		Mapping:  "AJDKSIRUXBLHWTMCQGZNPYFVOE",
+		Notches:  "E",
+		Machines: []string{"I"},
+	}))
+
+	rotor, rotorError := namespaced.GetRotor("lib:I")
+	assert.Nil(t, rotorError)
+	assert.Equal(t, "LIB:I", rotor.Name)
+
+	engine.SetRegistry(namespaced)
+	unchanged, encryptError := engine.Encrypt(plainText, fmt.Sprintf(key, "I", "B"))
+	assert.Nil(t, encryptError)
+	assert.Equal(t, expected, unchanged)
+
+	renamed, encryptError := engine.Encrypt(plainText, fmt.Sprintf(key, "LIB:I", "B"))
+	assert.Nil(t, encryptError)
+	assert.Equal(t, original, renamed)
+
+	// a namespaced machine names its own components without namespace
+	lab, registryError := settings.NewRegistry("lab")
+	assert.Nil(t, registryError)
+	assert.Nil(t, lab.LoadReader(strings.NewReader(`
+rotors:
+    X1:
+        mapping: EKMFLGDQVZNTOWYHXUSPAIBRCJ
+        notches: [Q]
+    X2:
+        mapping: AJDKSIRUXBLHWTMCQGZNPYFVOE
+        notches: [E]
+    X3:
+        mapping: BDFHJLCPRTXVZNYEIWGAKMUSQO
+        notches: [V]
+reflectors:
+    Y:
+        mapping: YRUHQSLDPXNGOKMIEBFZCWVJAT
+        machines: [LABM]
+machines:
+    - name: LABM
+      entry_wheel: ETW
+      stepping: ratchet
+      slots:
+          - rotors: [X1, X4]
+          - rotors: [X2]
+          - rotors: [X3]
+      reflectors: [B]
+`)))
+	assert.Nil(t, lab.AddRotor(settings.RotorDefinition{
+		Name:     "X4",
+		Mapping:  "ESOVPZJAYQUIRHXLNFTGKDCMWB",
+		Notches:  "J",
+		Machines: []string{"LABM"},
+	}))
+
+	machine, machineError := lab.GetMachine("lab:labm")
+	assert.Nil(t, machineError)
+	assert.Equal(t, []string{"LAB:X1", "LAB:X4"}, machine.Slots[0].Rotors)
+	assert.Equal(t, []string{"B"}, machine.Reflectors)
+	assert.Equal(t, "ETW", machine.EntryWheel)
+
+	rotor, rotorError = lab.GetRotor("lab:x4")
+	assert.Nil(t, rotorError)
+	assert.Equal(t, []string{"LAB:LABM"}, rotor.Machines)
+
+	engine.SetRegistry(lab)
+	machineKey := "machine: LAB:LABM\n" + key
+	for _, rotors := range [][3]string{{"LAB:X1", "LAB:X2", "LAB:X3"}, {"LAB:X4", "LAB:X2", "LAB:X3"}} {
+		labKey := strings.Replace(strings.Replace(machineKey, "name: II\n", "name: "+rotors[1]+"\n", 1), "name: III\n", "name: "+rotors[2]+"\n", 1)
+		for _, reflector := range []string{"B", "LAB:Y"} {
+			_, encryptError = engine.Encrypt(plainText, fmt.Sprintf(labKey, rotors[0], reflector))
+			assert.Nil(t, encryptError, rotors, reflector)
+		}
+	}
+
+	labText, encryptError := engine.Encrypt(plainText, fmt.Sprintf(strings.Replace(strings.Replace(machineKey, "name: II\n", "name: LAB:X2\n", 1), "name: III\n", "name: LAB:X3\n", 1), "LAB:X1", "LAB:Y"))
+	assert.Nil(t, encryptError)
+	assert.Equal(t, expected, labText)
+
+	// invalid definitions
+	assert.NotNil(t, registry.AddRotor(settings.RotorDefinition{Name: "SHORT", Mapping: "ABC"}))
+	assert.NotNil(t, registry.AddRotor(settings.RotorDefinition{Name: "DUPLICATE", Mapping: "AACDEFGHIJKLMNOPQRSTUVWXYZ"}))
+	assert.NotNil(t, registry.AddRotor(settings.RotorDefinition{Name: "NOTCH", Mapping: "EKMFLGDQVZNTOWYHXUSPAIBRCJ", Notches: "1"}))
+	assert.NotNil(t, registry.AddReflector(settings.ReflectorDefinition{Name: "ROTOR", Mapping: "EKMFLGDQVZNTOWYHXUSPAIBRCJ"}))
+	assert.NotNil(t, registry.AddReflector(settings.ReflectorDefinition{Name: "FIXED", Mapping: "AYUHQSLDPXNGOKMIEBFZCWVJRT"}))
+	assert.NotNil(t, registry.AddReflector(settings.ReflectorDefinition{Name: "WIRED", Rewirable: true, Fixed: "BO", Mapping: "YRUHQSLDPXNGOKMIEBFZCWVJAT"}))
+	for _, fixed := range []string{"", "B", "BB", "B1", "BOX"} {
+		assert.NotNil(t, registry.AddReflector(settings.ReflectorDefinition{Name: "UNFIXED", Rewirable: true, Fixed: fixed}), fixed)
+	}
+	assert.NotNil(t, registry.LoadReader(strings.NewReader("rotors:\n    Z:\n        mapping: ABC\n")))
+	assert.NotNil(t, registry.LoadReader(strings.NewReader("unknown: {}\n")))
+	_, namespaceError := settings.NewRegistry("a:b")
+	assert.NotNil(t, namespaceError)
+}
+
+func TestConcurrency(t *testing.T) {
+	engines := make(map[[2]bool]*Enigma)
+	for _, item := range testCases {
+		flags := [2]bool{item.PreserveFormatting, item.PreserveCase}
+		if engines[flags] == nil {
+			engine, engineError := NewEnigma(item.PreserveFormatting, item.PreserveCase)
+			assert.Nil(t, engineError)
+			engines[flags] = engine
+		}
+	}
+
+	// one engine per configuration, shared by all goroutines
+	var group sync.WaitGroup
+	for range 8 {
+		for _, item := range testCases {
+			group.Add(1)
+			go func() {
+				defer group.Done()
+
+				engine := engines[[2]bool{item.PreserveFormatting, item.PreserveCase}]
+				result, encryptError := engine.Encrypt([]byte(item.Plain), item.Key)
+				assert.Nil(t, encryptError)
+				assert.Equal(t, item.Encrypted, string(result))
+
+				result, decryptError := engine.Decrypt([]byte(item.Encrypted), item.Key)
+				assert.Nil(t, decryptError)
+				assert.Equal(t, item.Decrypted, string(result))
+			}()
+		}
+	}
+
+	group.Wait()
+
+	// one setting, shared by all goroutines, stays untouched
+	var exported settings.ExportSetting
+	assert.Nil(t, exported.Parse(testCases[0].Key))
+
+	var setting settings.Setting
+	assert.Nil(t, setting.Import(exported))
+	before := setting.Export()
+
+	engine := engines[[2]bool{testCases[0].PreserveFormatting, testCases[0].PreserveCase}]
+	for range 16 {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+
+			result, encryptError := engine.EncryptWithSetting([]byte(testCases[0].Plain), &setting)
+			assert.Nil(t, encryptError)
+			assert.Equal(t, testCases[0].Encrypted, string(result))
+		}()
+	}
+
+	group.Wait()
+	assert.Equal(t, before, setting.Export())
+
+	// components are looked up while others are added
+	registry, registryError := settings.NewRegistry("concurrent")
+	assert.Nil(t, registryError)
+
+	for index := range 16 {
+		group.Add(2)
+		go func() {
+			defer group.Done()
+
+			assert.Nil(t, registry.AddRotor(settings.RotorDefinition{
+				Name:    fmt.Sprintf("R%d", index),
+				Mapping: "EKMFLGDQVZNTOWYHXUSPAIBRCJ",
+				Notches: "Q",
+			}))
+		}()
+
+		go func() {
+			defer group.Done()
+
+			var randomSetting settings.Setting
+			assert.Nil(t, randomSetting.Random())
+
+			_, rotorError := registry.GetRotor("I")
+			assert.Nil(t, rotorError)
+
+			_, uhrError := settings.GetUhr()
+			assert.Nil(t, uhrError)
+		}()
+	}
+
+	group.Wait()
+
+	for index := range 16 {
+		_, rotorError := registry.GetRotor(fmt.Sprintf("concurrent:R%d", index))
+		assert.Nil(t, rotorError)
+	}
+}
+
+func TestStream(t *testing.T) {
+	plainText := []byte(strings.Repeat("DASOBERKOMMANDODERWEHRMACHTGIBTBEKANNT", 7))
+
+	for _, preserveFormatting := range []bool{false, true} {
+		engine, engineError := NewEnigma(preserveFormatting, false)
+		assert.Nil(t, engineError)
+
+		var exported settings.ExportSetting
+		assert.Nil(t, exported.Parse(testCases[0].Key))
+
+		var setting settings.Setting
+		assert.Nil(t, setting.Import(exported))
+
+		expected, encryptError := engine.EncryptWithSetting(plainText, &setting)
+		assert.Nil(t, encryptError)
+
+		// the output does not depend on the chunk size
+		for _, chunkSize := range []int{1, 3, 5, 7, 64, 4096} {
+			var buffer bytes.Buffer
+			writer, writerError := engine.NewEncryptWriter(&buffer, &setting)
+			assert.Nil(t, writerError)
+
+			for start := 0; start < len(plainText); start += chunkSize {
+				_, writeError := writer.Write(plainText[start:min(start+chunkSize, len(plainText))])
+				assert.Nil(t, writeError)
+			}
+
+			assert.Nil(t, writer.Close())
+			assert.Equal(t, string(expected), buffer.String())
+
+			// the grouping in fives is not part of the cipher text
+			cipherText := buffer.String()
+			if !preserveFormatting {
+				cipherText = strings.Join(strings.Fields(cipherText), "")
+			}
+
+			reader, readerError := engine.NewDecryptReader(iotest.OneByteReader(strings.NewReader(cipherText)), &setting)
+			assert.Nil(t, readerError)
+
+			decrypted, readError := io.ReadAll(reader)
+			assert.Nil(t, readError)
+			if preserveFormatting {
+				assert.Equal(t, string(plainText), string(decrypted))
+			} else {
+				assert.Equal(t, string(plainText), strings.Join(strings.Fields(string(decrypted)), ""))
+			}
+
+			_, decryptError := engine.DecryptWithSetting(buffer.Bytes(), &setting)
+			assert.Equal(t, preserveFormatting, decryptError == nil)
+		}
+
+		// small destination buffers and resets
+		transformer, transformerError := engine.NewTransformer(&setting)
+		assert.Nil(t, transformerError)
+
+		for range 2 {
+			transformed, _, transformError := transform.Bytes(transformer, plainText)
+			assert.Nil(t, transformError)
+			assert.Equal(t, string(expected), string(transformed))
+		}
+
+		dst := make([]byte, 2)
+		nDst, nSrc, transformError := transformer.Transform(dst, plainText, false)
+		assert.Equal(t, transform.ErrShortDst, transformError)
+		assert.Equal(t, 2, nDst)
+		assert.Equal(t, 2, nSrc)
+
+		// invalid input stops the stream unless it is copied
+		_, _, transformError = transform.Bytes(transformer, []byte("ABC1"))
+		assert.Equal(t, preserveFormatting, transformError == nil)
+	}
+
+	// long output is grouped in fives and wrapped after 80 letters
+	engine, engineError := NewEnigma(false, false)
+	assert.Nil(t, engineError)
+
+	cipherText, encryptError := engine.Encrypt(plainText[:85], testCases[0].Key)
+	assert.Nil(t, encryptError)
+
+	lines := strings.Split(string(cipherText), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, 16, len(strings.Fields(lines[0])))
+	assert.Equal(t, "", strings.Join(strings.Fields(lines[1]), "")[5:])
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	benchmarkEncrypt(b, false)
+}
+
+func BenchmarkEncryptPrecomputed(b *testing.B) {
+	benchmarkEncrypt(b, true)
+}
+
+func benchmarkEncrypt(b *testing.B, precomputed bool) {
+	plainText := []byte(strings.Repeat("DASOBERKOMMANDODERWEHRMACHTGIBTBEKANNT", 100))
+
+	engine, engineError := NewEnigma(true, false)
+	assert.Nil(b, engineError)
+	engine.SetPrecomputed(precomputed)
+
+	var exported settings.ExportSetting
+	assert.Nil(b, exported.Parse(testCases[0].Key))
+
+	var setting settings.Setting
+	assert.Nil(b, setting.Import(exported))
+
+	b.SetBytes(int64(len(plainText)))
+	b.ResetTimer()
+	for range b.N {
+		_, encryptError := engine.EncryptWithSetting(plainText, &setting)
+		if encryptError != nil {
+			b.Fatal(encryptError)
+		}
+	}
+
+	b.ReportMetric(float64(b.N*len(plainText))/b.Elapsed().Seconds(), "letters/s")
+}
+
+func TestPrecomputed(t *testing.T) {
+	for _, item := range testCases {
+		engine, engineError := NewEnigma(item.PreserveFormatting, item.PreserveCase)
+		assert.Nil(t, engineError)
+		engine.SetPrecomputed(true)
+
+		result, encryptError := engine.Encrypt([]byte(item.Plain), item.Key)
+		assert.Nil(t, encryptError)
+		assert.Equal(t, item.Encrypted, string(result))
+	}
+
+	plainText := []byte(strings.Repeat("ANKUNFTXDESXAGENTENXMORGENXFRUEH", 40))
+	keys := []string{
+		// moving reflector
+		`
+machine: G
+rotors:
+    - name: II-G
+      position: S
+      ring_setting: J
+    - name: I-G
+      position: "N"
+      ring_setting: A
+    - name: III-G
+      position: U
+      ring_setting: K
+reflector: G
+reflector_position: W
+reflector_ring_setting: C
+`,
+		// fixed greek rotor
+		`
+rotors:
+    - name: Gamma
+      position: L
+      ring_setting: C
+    - name: VIII
+      position: X
+      ring_setting: M
+    - name: VI
+      position: Z
+      ring_setting: P
+    - name: II
+      position: A
+      ring_setting: S
+reflector: C-Thin
+plug_board:
+    A: T
+    B: L
+    D: F
+`,
+	}
+
+	engine, engineError := NewEnigma(true, false)
+	assert.Nil(t, engineError)
+
+	precomputedEngine, engineError := NewEnigma(true, false)
+	assert.Nil(t, engineError)
+	precomputedEngine.SetPrecomputed(true)
+
+	for _, key := range keys {
+		expected, encryptError := engine.Encrypt(plainText, key)
+		assert.Nil(t, encryptError)
+
+		// the second run reuses the scrambler
+		for range 2 {
+			result, precomputedError := precomputedEngine.Encrypt(plainText, key)
+			assert.Nil(t, precomputedError)
+			assert.Equal(t, string(expected), string(result))
+		}
+	}
+
+	// the scramblers kept are limited by their size, the least recently used go first
+	assert.Equal(t, 2*26*26*26*26*26, precomputedEngine.scramblerBytes)
+	for _, rotor := range []string{"I", "III", "IV", "V", "VII"} {
+		_, encryptError := precomputedEngine.Encrypt(plainText[:10], strings.Replace(keys[1], "name: II\n", "name: "+rotor+"\n", 1))
+		assert.Nil(t, encryptError)
+	}
+
+	assert.Len(t, precomputedEngine.recentScramblers, 5)
+	assert.Len(t, precomputedEngine.scramblers, 5)
+	assert.Equal(t, 5*26*26*26*26*26, precomputedEngine.scramblerBytes)
+	assert.LessOrEqual(t, precomputedEngine.scramblerBytes, maxScramblerBytes)
+
+	// one scrambler serves all ring settings
+	var exported settings.ExportSetting
+	assert.Nil(t, exported.Parse(keys[1]))
+
+	var setting settings.Setting
+	assert.Nil(t, setting.Import(exported))
+
+	scrambler, scramblerError := settings.NewScrambler(&setting)
+	assert.Nil(t, scramblerError)
+
+	for _, rotor := range setting.Rotors {
+		rotor.RingSetting = (rotor.RingSetting + 7) % 26
+	}
+
+	assert.True(t, scrambler.Fits(&setting))
+	for letter := range 26 {
+		assert.Equal(t, setting.Transform(letter), scrambler.Transform(&setting, letter))
+	}
+
+	setting.Rotors[0], setting.Rotors[1] = setting.Rotors[1], setting.Rotors[0]
+	assert.False(t, scrambler.Fits(&setting))
+}
+
+func BenchmarkScrambler(b *testing.B) {
+	var exported settings.ExportSetting
+	assert.Nil(b, exported.Parse(testCases[0].Key))
+
+	var setting settings.Setting
+	assert.Nil(b, setting.Import(exported))
+
+	scrambler, scramblerError := settings.NewScrambler(&setting)
+	assert.Nil(b, scramblerError)
+
+	b.ResetTimer()
+	for index := range b.N {
+		setting.Step()
+		scrambler.Transform(&setting, index%26)
+	}
+
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "letters/s")
+}
+
+func TestBareSetting(t *testing.T) {
+	orders := settings.RotorOrders([]string{"I", "II", "III", "IV"})
+	assert.Len(t, orders, 24)
+	assert.Equal(t, []string{"I", "II", "III"}, orders[0])
+	assert.Equal(t, []string{"IV", "III", "II"}, orders[23])
+	assert.Empty(t, settings.RotorOrders([]string{"I", "II"}))
+
+	// without plugs and at ring settings A the rotors scramble like the machine
+	engine, engineError := NewEnigma(false, false)
+	assert.Nil(t, engineError)
+
+	plainText := "WETTERVORHERSAGEXBISKAYA"
+	cipherText, encryptError := engine.Encrypt([]byte(plainText), "{rotors: [{name: II, position: K}, {name: V, position: X}, {name: III, position: P}], reflector: B}")
+	assert.Nil(t, encryptError)
+
+	setting, settingError := settings.NewBareSetting([]string{"II", "V", "III"}, "B")
+	assert.Nil(t, settingError)
+	assert.Nil(t, setting.Machine)
+
+	setting.Rotors.SetIndex(10*676 + 23*26 + 15)
+	assert.Equal(t, []int{10, 23, 15}, []int{setting.Rotors[0].Position, setting.Rotors[1].Position, setting.Rotors[2].Position})
+	assert.Equal(t, 10*676+23*26+15, setting.Rotors.Index())
+
+	var scrambled strings.Builder
+	for _, letter := range plainText {
+		setting.Rotors.Move()
+		scrambled.WriteByte(byte('A' + setting.Scramble(int(letter-'A'))))
+	}
+
+	assert.Equal(t, strings.Join(strings.Fields(string(cipherText)), ""), scrambled.String())
+
+	_, settingError = settings.NewBareSetting([]string{"II", "X", "III"}, "B")
+	assert.NotNil(t, settingError)
+
+	_, settingError = settings.NewBareSetting([]string{"II", "V", "III"}, "X")
+	assert.NotNil(t, settingError)
+}
+
+func TestParseSetting(t *testing.T) {
+	key := "{rotors: [{name: II, position: K, ring_setting: D}, {name: V, position: X}, {name: III, position: P}], reflector: B, plug_board: {A: Q, Q: A}}"
+
+	var setting settings.Setting
+	assert.Nil(t, setting.Parse(key))
+	assert.Equal(t, "II", setting.Rotors[0].Name)
+	assert.Equal(t, 3, setting.Rotors[0].RingSetting)
+
+	engine, engineError := NewEnigma(false, false)
+	assert.Nil(t, engineError)
+
+	fromKey, keyError := engine.Encrypt([]byte("WETTERVORHERSAGE"), key)
+	assert.Nil(t, keyError)
+	fromSetting, settingError := engine.EncryptWithSetting([]byte("WETTERVORHERSAGE"), &setting)
+	assert.Nil(t, settingError)
+	assert.Equal(t, fromKey, fromSetting)
+
+	assert.NotNil(t, setting.Parse("{rotors: ["))
+	assert.NotNil(t, setting.Parse("{rotors: [{name: II}, {name: X}, {name: III}], reflector: B}"))
+}
+
+func TestGenerator(t *testing.T) {
+	// the same seed draws the same settings
+	first := settings.NewGenerator(1944)
+	second := settings.NewGenerator(1944)
+	for range 5 {
+		firstSetting, firstError := first.Next()
+		assert.Nil(t, firstError)
+
+		secondSetting, secondError := second.Next()
+		assert.Nil(t, secondError)
+		assert.Equal(t, firstSetting.Export(), secondSetting.Export())
+		assert.Equal(t, firstSetting.IDGroups, secondSetting.IDGroups)
+	}
+
+	// a month following the rules of the key sheets
+	generator := settings.NewGenerator(10)
+	generator.Historical = true
+
+	var previous *settings.Setting
+	var orders []string
+	for range 31 {
+		setting, generateError := generator.Next()
+		assert.Nil(t, generateError)
+		assert.Equal(t, "I", setting.Machine.Name)
+		assert.Equal(t, 4, len(setting.IDGroups))
+
+		var order []string
+		for index, rotor := range setting.Rotors {
+			if previous != nil {
+				assert.NotEqual(t, previous.Rotors[index].Name, rotor.Name)
+			}
+
+			order = append(order, rotor.Name)
+		}
+
+		assert.NotContains(t, orders, strings.Join(order, " "))
+		orders = append(orders, strings.Join(order, " "))
+
+		plugs := setting.PlugBoard.Export()
+		assert.Equal(t, 2*settings.DefaultPlugPairs, len(plugs))
+		for plug, value := range plugs {
+			// seed 10 draws an A-Z plug without the wrap
+			assert.NotContains(t, []byte{1, 25}, max(plug[0], value[0])-min(plug[0], value[0]))
+		}
+
+		previous = setting
+	}
+
+	// other machines and plug counts
+	for _, machine := range []string{"M3", "M4", "K", "G", "T", "Railway"} {
+		generator = settings.NewGenerator(uint64(len(machine)))
+		generator.Machine = machine
+		generator.PlugPairs = 13
+
+		for range 10 {
+			setting, generateError := generator.Next()
+			assert.Nil(t, generateError)
+			assert.Equal(t, strings.ToUpper(machine), setting.Machine.Name)
+		}
+	}
+
+	generator = settings.NewGenerator(0)
+	generator.Reflectors = []string{"D"}
+	setting, generateError := generator.Next()
+	assert.Nil(t, generateError)
+	assert.Equal(t, "D", setting.Reflector.Name)
+
+	generator.PlugPairs = 14
+	_, generateError = generator.Next()
+	assert.NotNil(t, generateError)
+
+	// generated settings encrypt and decrypt
+	engine, engineError := NewEnigma(true, false)
+	assert.Nil(t, engineError)
+
+	cipherText, encryptError := engine.EncryptWithSetting([]byte("ANGRIFFXUMXVIERUHR"), setting)
+	assert.Nil(t, encryptError)
+
+	plainText, decryptError := engine.DecryptWithSetting(cipherText, setting)
+	assert.Nil(t, decryptError)
+	assert.Equal(t, "ANGRIFFXUMXVIERUHR", string(plainText))
+
+	// the uhr takes ten pairs at a drawn position
+	generator = settings.NewGenerator(27)
+	generator.Uhr = true
+	positions := make(map[int]bool)
+	for range 20 {
+		setting, generateError = generator.Next()
+		assert.Nil(t, generateError)
+		assert.NotNil(t, setting.PlugBoard.Uhr)
+		assert.Equal(t, settings.UhrPairs, len(setting.PlugBoard.Pairs))
+		positions[setting.PlugBoard.Uhr.Position] = true
+
+		cipherText, encryptError = engine.EncryptWithSetting([]byte("ANGRIFFXUMXVIERUHR"), setting)
+		assert.Nil(t, encryptError)
+
+		plainText, decryptError = engine.DecryptWithSetting(cipherText, setting)
+		assert.Nil(t, decryptError)
+		assert.Equal(t, "ANGRIFFXUMXVIERUHR", string(plainText))
+	}
+
+	assert.Greater(t, len(positions), 1)
+}
+
+func TestSession(t *testing.T) {
+	engine, engineError := NewEnigma(false, false)
+	assert.Nil(t, engineError)
+
+	var exported settings.ExportSetting
+	assert.Nil(t, exported.Parse(testCases[0].Key))
+
+	var setting settings.Setting
+	assert.Nil(t, setting.Import(exported))
+
+	plainText := []byte("DASOBERKOMMANDODERWEHRMACHTGIBTBEKANNT")
+	expected, encryptError := engine.EncryptWithSetting(plainText, &setting)
+	assert.Nil(t, encryptError)
+
+	// pressing keys one by one lights the same lamps
+	session, sessionError := engine.NewSession(&setting)
+	assert.Nil(t, sessionError)
+	assert.Equal(t, "ZHJ", session.Positions())
+
+	var lamps []byte
+	for _, key := range bytes.ToLower(plainText) {
+		lamp, pressError := session.Press(key)
+		assert.Nil(t, pressError)
+		lamps = append(lamps, lamp)
+	}
+
+	assert.Equal(t, strings.ReplaceAll(string(expected), " ", ""), string(lamps))
+	assert.Equal(t, "ZHJ", exported.Rotors[0].Position+exported.Rotors[1].Position+exported.Rotors[2].Position)
+
+	_, pressError := session.Press('1')
+	assert.NotNil(t, pressError)
+
+	// stepping back returns to the start, encrypting continues where the last call stopped
+	for range plainText {
+		assert.Nil(t, session.StepBack())
+	}
+
+	assert.Equal(t, "ZHJ", session.Positions())
+
+	first, firstError := session.Encrypt(plainText[:12])
+	assert.Nil(t, firstError)
+
+	second, secondError := session.Encrypt(plainText[12:])
+	assert.Nil(t, secondError)
+	assert.Equal(t, strings.ReplaceAll(string(expected), " ", ""), strings.ReplaceAll(string(first)+string(second), " ", ""))
+
+	for range 14 {
+		assert.Nil(t, session.StepBack())
+	}
+
+	ungrouped := strings.ReplaceAll(string(expected), " ", "")
+	decrypted, decryptError := session.Decrypt([]byte(ungrouped[len(ungrouped)-14:]))
+	assert.Nil(t, decryptError)
+	assert.Equal(t, "CHTGI BTBEK ANNT", string(decrypted))
+
+	assert.Nil(t, session.SetRingSettings("aaa"))
+	assert.Equal(t, "AAA", session.RingSettings())
+	assert.NotNil(t, session.SetPositions("AB"))
+	assert.NotNil(t, session.SetPositions("AB1"))
+	assert.NotNil(t, session.SetRingSettings("A"))
+
+	session.Reset()
+	assert.Equal(t, "ZHJ", session.Positions())
+	assert.Equal(t, "LYH", session.RingSettings())
+	assert.Equal(t, setting.Export(), session.Setting().Export())
+
+	// stepping back after setting the positions by hand, through the double step of rotor II at E
+	session, sessionError = engine.NewSession(testSetting(t, "{rotors: [{name: I}, {name: II}, {name: III}], reflector: B}"))
+	assert.Nil(t, sessionError)
+	assert.Nil(t, session.SetPositions("ADU"))
+	for _, positions := range []string{"ADV", "AEW", "BFX", "BFY"} {
+		session.Step()
+		assert.Equal(t, positions, session.Positions())
+	}
+
+	// BFX follows BFW and AEW, BFW only AEV and nothing leads to AEV
+	assert.Nil(t, session.SetPositions("BFY"))
+	for _, positions := range []string{"BFX", "BFW", "AEV"} {
+		assert.Nil(t, session.StepBack())
+		assert.Equal(t, positions, session.Positions())
+	}
+
+	assert.NotNil(t, session.StepBack())
+	assert.Equal(t, "AEV", session.Positions())
+}
+
+func testSetting(t *testing.T, key string) *settings.Setting {
+	var exported settings.ExportSetting
+	assert.Nil(t, exported.Parse(key))
+
+	var setting settings.Setting
+	assert.Nil(t, setting.Import(exported))
+
+	return &setting
+}
+
+func TestTrace(t *testing.T) {
+	engine, engineError := NewEnigma(false, false)
+	assert.Nil(t, engineError)
+
+	setting := testSetting(t, `{rotors: [{name: I, position: A}, {name: II, position: D}, {name: III, position: U}],
+		reflector: B, plug_board: {A: Z}}`)
+
+	expected, encryptError := engine.EncryptWithSetting([]byte("AAAA"), setting)
+	assert.Nil(t, encryptError)
+
+	var recorder TraceRecorder
+	engine.SetTracer(recorder.Record)
+	engine.SetPrecomputed(true)
+
+	traced, tracedError := engine.EncryptWithSetting([]byte("AAAA"), setting)
+	assert.Nil(t, tracedError)
+	assert.Equal(t, string(expected), string(traced))
+
+	// ADU ADV AEW BFX BFY, the middle rotor steps on its own notch at AEW
+	traces := recorder.Traces()
+	assert.Equal(t, 4, len(traces))
+	for index, expectedStep := range []struct {
+		before     string
+		after      string
+		stepped    []string
+		doubleStep bool
+	}{
+		{"ADU", "ADV", []string{"III"}, false},
+		{"ADV", "AEW", []string{"II", "III"}, false},
+		{"AEW", "BFX", []string{"I", "II", "III"}, true},
+		{"BFX", "BFY", []string{"III"}, false},
+	} {
+		trace := traces[index]
+		assert.Equal(t, expectedStep.before, trace.Before)
+		assert.Equal(t, expectedStep.after, trace.After)
+		assert.Equal(t, expectedStep.stepped, trace.Stepped)
+		assert.Equal(t, expectedStep.doubleStep, trace.DoubleStep)
+		assert.Equal(t, "A", trace.Input)
+		assert.Equal(t, string(expected[index]), trace.Output)
+
+		// plug board, entry wheel, three rotors, reflector and back
+		assert.Equal(t, 11, len(trace.Path))
+		assert.Equal(t, "Z", trace.Path[0].Out)
+		assert.Equal(t, Reflect, trace.Path[5].Direction)
+		assert.Equal(t, "B", trace.Path[5].Component)
+		for step := 1; step < len(trace.Path); step++ {
+			assert.Equal(t, trace.Path[step-1].Out, trace.Path[step].In)
+		}
+
+		assert.Equal(t, trace.Output, trace.Path[len(trace.Path)-1].Out)
+	}
+
+	assert.True(t, strings.HasPrefix(traces[2].String(), "A AEW>BFX (double step): A > plug board > Z > ETW"))
+
+	encoded, marshalError := json.Marshal(traces)
+	assert.Nil(t, marshalError)
+	assert.Contains(t, string(encoded), `"double_step":true`)
+
+	var decoded []Trace
+	assert.Nil(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, traces, decoded)
+
+	// sessions trace key presses as well
+	recorder.Reset()
+	session, sessionError := engine.NewSession(setting)
+	assert.Nil(t, sessionError)
+
+	lamp, pressError := session.Press('A')
+	assert.Nil(t, pressError)
+	assert.Equal(t, expected[0], lamp)
+	assert.Equal(t, 1, len(recorder.Traces()))
+
+	engine.SetTracer(nil)
+	_, encryptError = engine.EncryptWithSetting([]byte("AAAA"), setting)
+	assert.Nil(t, encryptError)
+	assert.Equal(t, 1, len(recorder.Traces()))
+}
+
+func TestKeySheet(t *testing.T) {
+	// the tabular sheet gives the same settings as the hand converted yaml, with the days
+	var parsed settings.Settings
+	assert.Nil(t, parsed.ParseSheet(embed.SettingsOct1944Txt))
+	assert.Equal(t, 31, len(parsed))
+
+	converted, settingsError := settings.GetSettings()
+	assert.Nil(t, settingsError)
+
+	for index := range parsed {
+		assert.Equal(t, 31-index, parsed[index].Day)
+		assert.Equal(t, converted[index].Day, parsed[index].Day)
+		assert.Equal(t, converted[index].IDGroups, parsed[index].IDGroups)
+		assert.Equal(t, converted[index].PlugBoard.Pairs, parsed[index].PlugBoard.Pairs)
+		assert.Equal(t, converted[index].Export(), parsed[index].Export())
+	}
+
+	day, dayError := parsed.GetDay(29)
+	assert.Nil(t, dayError)
+	assert.Equal(t, "II V IV", strings.Join([]string{day.Rotors[0].Name, day.Rotors[1].Name, day.Rotors[2].Name}, " "))
+	assert.Equal(t, []int{18, 8, 23}, []int{day.Rotors[0].RingSetting, day.Rotors[1].RingSetting, day.Rotors[2].RingSetting})
+	assert.Equal(t, "ZU HL CQ WM OA PY EB TR DN VI", strings.Join(day.PlugBoard.Pairs, " "))
+	assert.Equal(t, []string{"nci", "oid", "yhp", "nip"}, day.IDGroups)
+
+	// the copy does not change the sheet
+	day.Rotors[0].Position = 5
+	again, _ := parsed.GetDay(29)
+	assert.Equal(t, 0, again.Rotors[0].Position)
+
+	_, dayError = parsed.GetDay(32)
+	assert.NotNil(t, dayError)
+
+	// a transcribed sheet with odd spacing and indented lines
+	var transcribed settings.Settings
+	assert.Nil(t, transcribed.ParseSheet([]byte("Nr. 29\n\nSt 2.  I II III 01 02 03 AB CD  abc def ghi jkl\nSt 1. V IV III 26 25 24 abc bcd cde def\n"+
+		"  St 3. II III I 04 05 06 EF abc def ghi jkl\n\tSt  4. III I II 07 08 09 GH abc def ghi jkl\n")))
+	assert.Equal(t, 4, len(transcribed))
+	assert.Equal(t, 2, transcribed[0].Day)
+	assert.Equal(t, []string{"AB", "CD"}, transcribed[0].PlugBoard.Pairs)
+	assert.False(t, transcribed[1].PlugBoard.Plugged())
+	assert.Equal(t, 3, transcribed[2].Day)
+	assert.Equal(t, "II", transcribed[2].Rotors[0].Name)
+	assert.Equal(t, 4, transcribed[3].Day)
+	assert.Equal(t, []string{"GH"}, transcribed[3].PlugBoard.Pairs)
+
+	for _, invalid := range []string{
+		"",
+		"Oktober 1944",
+		"St 1. IV V I 21 15 abc def ghi jkl",
+		"St 1. IV V I 21 15 27 AB abc def ghi jkl",
+		"St 1. IV V X 21 15 16 AB abc def ghi jkl",
+		"St 1. IV V I 21 15 16 AB AC abc def ghi jkl",
+		"St 1. IV V I 21 15 16 AB abc def ghi",
+	} {
+		var invalidSheet settings.Settings
+		assert.NotNil(t, invalidSheet.ParseSheet([]byte(invalid)), invalid)
+	}
+}
+
+func TestKeySheetGenerator(t *testing.T) {
+	generator := settings.NewGenerator(1944)
+	generator.Historical = true
+	month, monthError := generator.Month(31)
+	assert.Nil(t, monthError)
+	assert.Equal(t, 31, len(month))
+
+	// the last day first, Kenngruppen and rotor orders unique within the month
+	idGroups := make(map[string]bool)
+	orders := make(map[string]bool)
+	for index := range month {
+		day := &month[index]
+		assert.Equal(t, 31-index, day.Day)
+		assert.Equal(t, 4, len(day.IDGroups))
+		assert.Equal(t, settings.DefaultPlugPairs, len(day.PlugBoard.Plugs()))
+
+		for _, idGroup := range day.IDGroups {
+			assert.False(t, idGroups[idGroup], idGroup)
+			idGroups[idGroup] = true
+		}
+
+		order := strings.Fields(day.SheetColumns()[1])
+		assert.False(t, orders[strings.Join(order, " ")])
+		orders[strings.Join(order, " ")] = true
+
+		// no rotor in the same slot as on the day before
+		if index > 0 {
+			for slot, rotor := range month[index-1].Rotors {
+				assert.NotEqual(t, rotor.Name, day.Rotors[slot].Name)
+			}
+		}
+	}
+
+	// the tabular layout and the yaml schema read back to the same days
+	var parsed settings.Settings
+	assert.Nil(t, parsed.ParseSheet([]byte(month.Sheet("Maschinenschlüssel Nr. 1"))))
+	assert.Equal(t, len(month), len(parsed))
+
+	data, yamlError := month.YAML()
+	assert.Nil(t, yamlError)
+	assert.True(t, strings.HasPrefix(string(data), "- day: 31\n"))
+
+	var loaded settings.Settings
+	assert.Nil(t, loaded.Load(data))
+	assert.Equal(t, len(month), len(loaded))
+
+	for index := range month {
+		assert.Equal(t, month[index].SheetLine(), parsed[index].SheetLine())
+		assert.Equal(t, month[index].SheetLine(), loaded[index].SheetLine())
+		assert.Equal(t, month[index].PlugBoard.Mapping, loaded[index].PlugBoard.Mapping)
+		assert.Equal(t, month[index].Reflector.Name, loaded[index].Reflector.Name)
+	}
+
+	// the oktober 1944 sheet prints as transcribed
+	converted, settingsError := settings.GetSettings()
+	assert.Nil(t, settingsError)
+	assert.Equal(t, "St 31.  IV V I      21 15 16  KL IT FQ HY XC NP VZ JB SE OG  jkm ogi ncj glp", converted[0].SheetLine())
+
+	var table bytes.Buffer
+	assert.Nil(t, month.CSV(&table))
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	assert.Equal(t, 32, len(lines))
+	assert.Equal(t, "Datum,Walzenlage,Ringstellung,Steckerverbindungen,Kenngruppen", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "31,"))
+
+	var page bytes.Buffer
+	assert.Nil(t, month.HTML(&page, "Maschinenschlüssel <Nr. 1>"))
+	assert.Contains(t, page.String(), "Maschinenschlüssel &lt;Nr. 1&gt;")
+	assert.Less(t, strings.Index(page.String(), "St 31."), strings.Index(page.String(), "St 30."))
+	assert.Less(t, strings.Index(page.String(), "St 10."), strings.Index(page.String(), "St  1."))
+
+	// the last day first also from ascending days, the days given stay in their order
+	ascending := slices.Clone(month)
+	slices.Reverse(ascending)
+	page.Reset()
+	assert.Nil(t, ascending.HTML(&page, ""))
+	assert.Less(t, strings.Index(page.String(), "St 31."), strings.Index(page.String(), "St 30."))
+	assert.Less(t, strings.Index(page.String(), "St 10."), strings.Index(page.String(), "St  9."))
+	assert.Less(t, strings.Index(page.String(), "St  2."), strings.Index(page.String(), "St  1."))
+	assert.Equal(t, 1, ascending[0].Day)
+
+	for _, days := range []int{0, 32} {
+		_, monthError = generator.Month(days)
+		assert.NotNil(t, monthError)
+	}
+
+	// the uhrstellung follows the plugs and reads back from the tabular layout and the yaml schema
+	generator = settings.NewGenerator(40)
+	generator.Historical = true
+	generator.Uhr = true
+	month, monthError = generator.Month(5)
+	assert.Nil(t, monthError)
+
+	parsed = nil
+	assert.Nil(t, parsed.ParseSheet([]byte(month.Sheet(""))))
+
+	data, yamlError = month.YAML()
+	assert.Nil(t, yamlError)
+
+	loaded = nil
+	assert.Nil(t, loaded.Load(data))
+
+	for index := range month {
+		assert.Regexp(t, `^St  \d\.  .*  ([A-Z]{2} ){10}Uhr \d{2}  `, month[index].SheetLine())
+		assert.Equal(t, month[index].PlugBoard.Uhr.Position, parsed[index].PlugBoard.Uhr.Position)
+		assert.Equal(t, month[index].PlugBoard.Mapping, parsed[index].PlugBoard.Mapping)
+		assert.Equal(t, month[index].PlugBoard.Mapping, loaded[index].PlugBoard.Mapping)
+		assert.Equal(t, month[index].SheetLine(), loaded[index].SheetLine())
+	}
+
+	var invalidSheet settings.Settings
+	assert.NotNil(t, invalidSheet.ParseSheet([]byte("St 1. IV V I 21 15 16 KL IT FQ HY XC NP VZ JB SE OG Uhr xx jkm ogi ncj glp")))
+}
+
+func TestKeySheets(t *testing.T) {
+	// the embedded sheet with its title
+	embedded, sheetsError := settings.GetKeySheets()
+	assert.Nil(t, sheetsError)
+	assert.Equal(t, 1, len(embedded))
+	assert.Equal(t, "Armee-Stabs-Maschinenschlüssel Nr. 28", embedded[0].Name())
+	assert.Equal(t, time.October, embedded[0].Month)
+	assert.Equal(t, 1944, embedded[0].Year)
+
+	setting, sheet, dateError := embedded.ForDate(time.Date(1944, time.October, 30, 22, 20, 0, 0, time.UTC))
+	assert.Nil(t, dateError)
+	assert.Equal(t, embedded[0], sheet)
+	assert.Equal(t, 30, setting.Day)
+	assert.Equal(t, []string{"ino", "udl", "nam", "lax"}, setting.IDGroups)
+
+	_, _, dateError = embedded.ForDate(time.Date(1944, time.November, 1, 0, 0, 0, 0, time.UTC))
+	assert.NotNil(t, dateError)
+
+	// sheets of another network from disk, as YAML and in the tabular layout
+	directory := t.TempDir()
+	yamlFile := filepath.Join(directory, "luftwaffe.yaml")
+	assert.Nil(t, os.WriteFile(yamlFile, []byte(`network: Luftwaffen-Maschinenschlüssel
+number: 7
+month: Oktober
+year: 1944
+days:
+  - day: 30
+    id_groups: [abc, def, ghi, jkl]
+    rotors: [{I: 1}, {II: 2}, {III: 3}]
+    reflector: B
+    plug_board: AB CD
+`), 0o600))
+
+	generator := settings.NewGenerator(1944)
+	generator.Historical = true
+	november, monthError := generator.Month(30)
+	assert.Nil(t, monthError)
+
+	title := (&settings.KeySheet{Network: "Armee-Stabs-Maschinenschlüssel", Number: 29, Month: time.November, Year: 1944}).Title()
+	textFile := filepath.Join(directory, "november.txt")
+	assert.Nil(t, os.WriteFile(textFile, []byte(november.Sheet(title)), 0o600))
+
+	sheets := slices.Clone(embedded)
+	assert.Nil(t, sheets.LoadFile(yamlFile))
+	assert.Nil(t, sheets.LoadFile(textFile))
+	assert.Equal(t, "Armee-Stabs-Maschinenschlüssel Nr. 29", sheets[2].Name())
+	assert.Equal(t, time.November, sheets[2].Month)
+	assert.Equal(t, 30, len(sheets[2].Days))
+
+	// two networks on the same day need a choice
+	_, _, dateError = sheets.ForDate(time.Date(1944, time.October, 30, 0, 0, 0, 0, time.UTC))
+	assert.NotNil(t, dateError)
+
+	setting, sheet, dateError = sheets.Network("luftwaffen-maschinenschlüssel").ForDate(time.Date(1944, time.October, 30, 0, 0, 0, 0, time.UTC))
+	assert.Nil(t, dateError)
+	assert.Equal(t, 7, sheet.Number)
+	assert.Equal(t, []string{"abc", "def", "ghi", "jkl"}, setting.IDGroups)
+
+	_, _, dateError = sheets.Network("Luftwaffen-Maschinenschlüssel Nr. 7").ForDate(time.Date(1944, time.October, 29, 0, 0, 0, 0, time.UTC))
+	assert.NotNil(t, dateError)
+
+	setting, sheet, dateError = sheets.ForDate(time.Date(1944, time.November, 5, 0, 0, 0, 0, time.UTC))
+	assert.Nil(t, dateError)
+	assert.Equal(t, 29, sheet.Number)
+	assert.Equal(t, november[25].SheetLine(), setting.SheetLine())
+
+	for _, invalid := range []string{
+		"network: X\nmonth: 11\nyear: 1944\ndays: [{day: 31, rotors: [{I: 1}, {II: 2}, {III: 3}], reflector: B}]",
+		"network: X\nmonth: 13\nyear: 1944\ndays: [{day: 1, rotors: [{I: 1}, {II: 2}, {III: 3}], reflector: B}]",
+		"network: X\nmonth: 11\ndays: [{day: 1, rotors: [{I: 1}, {II: 2}, {III: 3}], reflector: B}]",
+		"network: X\nmonth: 11\nyear: 1944",
+		"- {day: 2, rotors: [{I: 1}, {II: 2}, {III: 3}], reflector: B}\n- {day: 2, rotors: [{I: 1}, {II: 2}, {III: 3}], reflector: B}",
+		"Nr. 1\nfür Februar 1943\n\nSt 29. I II III 01 02 03 AB abc def ghi jkl",
+	} {
+		var invalidSheet settings.KeySheet
+		assert.NotNil(t, invalidSheet.Parse([]byte(invalid)), invalid)
+	}
+
+	assert.NotNil(t, sheets.LoadFile(filepath.Join(directory, "missing.txt")))
+}
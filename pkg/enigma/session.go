@@ -0,0 +1,170 @@
+package enigma
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"strings"
+)
+
+// Session is a machine that keeps its rotor positions between calls, for typing one key at a time or encrypting a
+// message in several parts, like the message key and then the text. It is not safe for concurrent use.
+type Session struct {
+	transformer *Transformer
+	history     [][]int // rotor positions before each step, for stepping back exactly
+}
+
+func (what *Enigma) NewSession(setting *settings.Setting) (*Session, error) {
+	transformer, transformerError := what.NewTransformer(setting)
+	if transformerError != nil {
+		return nil, transformerError
+	}
+
+	return &Session{
+		transformer: transformer,
+	}, nil
+}
+
+// Press steps the rotors and returns the lamp lit by the key, always upper case.
+func (what *Session) Press(letter byte) (byte, error) {
+	index := letterIndex(letter)
+	if index < 0 {
+		return 0, fmt.Errorf("invalid key %q", letter)
+	}
+
+	what.history = append(what.history, what.positions())
+
+	return defs.UpperCase[what.transformer.press(index)], nil
+}
+
+// Encrypt continues from the current positions, the output is grouped like the one of Enigma.Encrypt.
+func (what *Session) Encrypt(plainText []byte) ([]byte, error) {
+	what.transformer.letters = 0
+	out := make([]byte, 0, len(plainText)+len(plainText)/4)
+	for _, inByte := range plainText {
+		before := what.positions()
+		letters := what.transformer.letters
+
+		var appendError error
+		out, appendError = what.transformer.appendByte(out, inByte)
+		if appendError != nil {
+			return nil, appendError
+		}
+
+		if what.transformer.letters != letters {
+			what.history = append(what.history, before)
+		}
+	}
+
+	return out, nil
+}
+
+// Decrypt continues from the current positions, it is the same as Encrypt.
+func (what *Session) Decrypt(cipherText []byte) ([]byte, error) {
+	return what.Encrypt(cipherText)
+}
+
+// Positions returns the letters in the rotor windows from left to right.
+func (what *Session) Positions() string {
+	return windowLetters(what.transformer.machine)
+}
+
+// SetPositions turns the rotors by hand, one letter per rotor from left to right.
+func (what *Session) SetPositions(positions string) error {
+	rotors := what.transformer.machine.Rotors
+	if len(positions) != len(rotors) {
+		return fmt.Errorf("invalid positions %q, expected %d letters", positions, len(rotors))
+	}
+
+	for index := range positions {
+		if letterIndex(positions[index]) < 0 {
+			return fmt.Errorf("invalid position %q", positions[index])
+		}
+	}
+
+	for index, rotor := range rotors {
+		rotor.Position = letterIndex(positions[index])
+	}
+
+	what.history = nil
+
+	return nil
+}
+
+// RingSettings returns the ring settings as letters from left to right.
+func (what *Session) RingSettings() string {
+	var builder strings.Builder
+	for _, rotor := range what.transformer.machine.Rotors {
+		builder.WriteByte(defs.UpperCase[rotor.RingSetting])
+	}
+
+	return builder.String()
+}
+
+// SetRingSettings changes the ring settings in place, one letter per rotor from left to right. The window letters stay.
+func (what *Session) SetRingSettings(ringSettings string) error {
+	rotors := what.transformer.machine.Rotors
+	if len(ringSettings) != len(rotors) {
+		return fmt.Errorf("invalid ring settings %q, expected %d letters", ringSettings, len(rotors))
+	}
+
+	for index := range ringSettings {
+		if letterIndex(ringSettings[index]) < 0 {
+			return fmt.Errorf("invalid ring setting %q", ringSettings[index])
+		}
+	}
+
+	for index, rotor := range rotors {
+		rotor.RingSetting = letterIndex(ringSettings[index])
+	}
+
+	return nil
+}
+
+// Step moves the rotors as a key press would, without lighting a lamp.
+func (what *Session) Step() {
+	what.history = append(what.history, what.positions())
+	what.transformer.machine.Step()
+}
+
+// StepBack undoes a step. After a step of this session the rotors return exactly, after turning them by hand the
+// previous positions are worked out from the stepping, see settings.Setting.StepBack.
+func (what *Session) StepBack() error {
+	if len(what.history) > 0 {
+		last := len(what.history) - 1
+		what.setPositions(what.history[last])
+		what.history = what.history[:last]
+
+		return nil
+	}
+
+	return what.transformer.machine.StepBack()
+}
+
+// Reset puts the rotors back to the setting the session was created with.
+func (what *Session) Reset() {
+	what.transformer.Reset()
+	what.history = nil
+}
+
+// Setting returns a copy of the machine at the current positions.
+func (what *Session) Setting() *settings.Setting {
+	return what.transformer.machine.Copy()
+}
+
+func (what *Session) positions() []int {
+	positions := make([]int, 0, len(what.transformer.machine.Rotors)+1)
+	for _, rotor := range what.transformer.machine.Rotors {
+		positions = append(positions, rotor.Position)
+	}
+
+	return append(positions, what.transformer.machine.Reflector.Position)
+}
+
+func (what *Session) setPositions(positions []int) {
+	for index, rotor := range what.transformer.machine.Rotors {
+		rotor.Position = positions[index]
+	}
+
+	what.transformer.machine.Reflector.Position = positions[len(positions)-1]
+}
@@ -0,0 +1,170 @@
+package enigma
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Tracer receives the trace of every key press. The engine calls it from the goroutine doing the work, so a tracer
+// shared between goroutines must synchronize itself, like TraceRecorder does.
+type Tracer func(trace Trace)
+
+// Direction is the way the signal runs through a component.
+type Direction string
+
+const (
+	Forward  Direction = "forward"  // from the keyboard towards the reflector
+	Reflect  Direction = "reflect"  // at the reflector
+	Backward Direction = "backward" // from the reflector back to the lamps
+)
+
+// Trace follows a single key press: the stepping first, then the letter through every component.
+type Trace struct {
+	Input      string      `json:"input"`                 // Taste
+	Before     string      `json:"before"`                // window letters before the key press
+	After      string      `json:"after"`                 // window letters the letter is encrypted at
+	Stepped    []string    `json:"stepped,omitempty"`     // rotors that moved, left to right
+	DoubleStep bool        `json:"double_step"`           // a middle rotor moved on its own notch
+	Path       []TraceStep `json:"path"`                  // components from the keyboard to the lamps
+	Output     string      `json:"output"`                // Lampe
+	Reflector  string      `json:"reflector,omitempty"`   // position of a settable reflector
+	UhrPlugged bool        `json:"uhr_plugged,omitempty"` // the plug board runs through the uhr
+}
+
+// TraceStep is the letter entering and leaving a component.
+type TraceStep struct {
+	Component string    `json:"component"`
+	Direction Direction `json:"direction"`
+	In        string    `json:"in"`
+	Out       string    `json:"out"`
+}
+
+// TraceRecorder collects traces, it is safe for concurrent use.
+type TraceRecorder struct {
+	mutex  sync.Mutex
+	traces []Trace
+}
+
+func (what *TraceRecorder) Record(trace Trace) {
+	what.mutex.Lock()
+	defer what.mutex.Unlock()
+
+	what.traces = append(what.traces, trace)
+}
+
+// Traces returns the traces recorded so far.
+func (what *TraceRecorder) Traces() []Trace {
+	what.mutex.Lock()
+	defer what.mutex.Unlock()
+
+	return slices.Clone(what.traces)
+}
+
+func (what *TraceRecorder) Reset() {
+	what.mutex.Lock()
+	defer what.mutex.Unlock()
+
+	what.traces = nil
+}
+
+// String shows the stepping and the path in one line, like "A ADU>ADV: A > plug board > A > ETW > A > III > ...".
+func (what *Trace) String() string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%v %v>%v", what.Input, what.Before, what.After))
+	if what.DoubleStep {
+		builder.WriteString(" (double step)")
+	}
+
+	builder.WriteString(": " + what.Input)
+	for _, step := range what.Path {
+		builder.WriteString(fmt.Sprintf(" > %v > %v", step.Component, step.Out))
+	}
+
+	return builder.String()
+}
+
+// trace steps the machine and runs the letter through it one component at a time.
+func trace(machine *settings.Setting, letter int) (Trace, int) {
+	before := windowLetters(machine)
+	positions := make([]int, len(machine.Rotors))
+	for index, rotor := range machine.Rotors {
+		positions[index] = rotor.Position
+	}
+
+	machine.Step()
+
+	result := Trace{
+		Input:      string(defs.UpperCase[letter]),
+		Before:     before,
+		After:      windowLetters(machine),
+		UhrPlugged: machine.PlugBoard.Uhr != nil,
+	}
+
+	if machine.Reflector.Settable {
+		result.Reflector = string(defs.UpperCase[machine.Reflector.Position])
+	}
+
+	for index, rotor := range machine.Rotors {
+		if rotor.Position == positions[index] {
+			continue
+		}
+
+		result.Stepped = append(result.Stepped, rotor.Name)
+
+		// a rotor moving although the one to its right was not at a notch was pushed at its own notch
+		right := index + 1
+		if right < len(machine.Rotors) && !slices.Contains(machine.Rotors[right].Notches, positions[right]) {
+			result.DoubleStep = true
+		}
+	}
+
+	add := func(component string, direction Direction, in int, out int) int {
+		result.Path = append(result.Path, TraceStep{
+			Component: component,
+			Direction: direction,
+			In:        string(defs.UpperCase[in]),
+			Out:       string(defs.UpperCase[out]),
+		})
+
+		return out
+	}
+
+	letter = add("plug board", Forward, letter, machine.PlugBoard.Encrypt(letter))
+	letter = add(entryWheelName(machine), Forward, letter, machine.EntryWheel.Encrypt(letter))
+	for index := len(machine.Rotors) - 1; index >= 0; index-- {
+		letter = add(machine.Rotors[index].Name, Forward, letter, machine.Rotors[index].Encrypt(letter))
+	}
+
+	letter = add(machine.Reflector.Name, Reflect, letter, machine.Reflector.Reflect(letter))
+	for _, rotor := range machine.Rotors {
+		letter = add(rotor.Name, Backward, letter, rotor.Decrypt(letter))
+	}
+
+	letter = add(entryWheelName(machine), Backward, letter, machine.EntryWheel.Decrypt(letter))
+	letter = add("plug board", Backward, letter, machine.PlugBoard.Decrypt(letter))
+
+	result.Output = string(defs.UpperCase[letter])
+
+	return result, letter
+}
+
+func windowLetters(machine *settings.Setting) string {
+	var builder strings.Builder
+	for _, rotor := range machine.Rotors {
+		builder.WriteByte(defs.UpperCase[rotor.Position])
+	}
+
+	return builder.String()
+}
+
+func entryWheelName(machine *settings.Setting) string {
+	if len(machine.EntryWheel.Name) == 0 {
+		return "ETW"
+	}
+
+	return "ETW " + machine.EntryWheel.Name
+}
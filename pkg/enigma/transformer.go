@@ -0,0 +1,179 @@
+package enigma
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"golang.org/x/text/transform"
+	"io"
+)
+
+// Transformer runs a stream through the machine. The rotor positions and the grouping of the output carry over from
+// one chunk to the next, so the result does not depend on how the input is split.
+type Transformer struct {
+	preserveFormatting bool
+	preserveCase       bool
+	initial            *settings.Setting
+	machine            *settings.Setting
+	scrambler          *settings.Scrambler // precomputed permutations, optional
+	tracer             Tracer              // receives every key press, optional
+	letters            int                 // letters written so far
+}
+
+func (what *Enigma) NewTransformer(setting *settings.Setting) (*Transformer, error) {
+	if setting == nil {
+		return nil, fmt.Errorf("no setting")
+	}
+
+	transformer := &Transformer{
+		preserveFormatting: what.preserveFormatting,
+		preserveCase:       what.preserveCase,
+		initial:            setting.Copy(),
+		tracer:             what.tracer,
+	}
+
+	if what.precomputed {
+		scrambler, scramblerError := what.scrambler(setting)
+		if scramblerError != nil {
+			return nil, scramblerError
+		}
+
+		transformer.scrambler = scrambler
+	}
+
+	transformer.Reset()
+
+	return transformer, nil
+}
+
+// NewEncryptWriter encrypts everything written to it into writer, Close flushes the remaining output.
+func (what *Enigma) NewEncryptWriter(writer io.Writer, setting *settings.Setting) (io.WriteCloser, error) {
+	transformer, transformerError := what.NewTransformer(setting)
+	if transformerError != nil {
+		return nil, transformerError
+	}
+
+	return transform.NewWriter(writer, transformer), nil
+}
+
+// NewDecryptReader decrypts everything read from reader.
+func (what *Enigma) NewDecryptReader(reader io.Reader, setting *settings.Setting) (io.Reader, error) {
+	transformer, transformerError := what.NewTransformer(setting)
+	if transformerError != nil {
+		return nil, transformerError
+	}
+
+	return transform.NewReader(reader, transformer), nil
+}
+
+// Reset puts the rotors back to the setting the transformer was created with.
+func (what *Transformer) Reset() {
+	what.machine = what.initial.Copy()
+	what.letters = 0
+}
+
+func (what *Transformer) Transform(dst []byte, src []byte, _ bool) (int, int, error) {
+	nDst := 0
+	for nSrc, inByte := range src {
+		if nDst+what.width(inByte) > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+
+		out, appendError := what.appendByte(dst[:nDst], inByte)
+		if appendError != nil {
+			return nDst, nSrc, appendError
+		}
+
+		nDst = len(out)
+	}
+
+	return nDst, len(src), nil
+}
+
+// appendByte appends the output for a single input byte, grouping the letters in fives and lines of 80 letters unless
+// the formatting is preserved.
+func (what *Transformer) appendByte(out []byte, inByte byte) ([]byte, error) {
+	if what.preserveFormatting && !what.shouldEncrypt(inByte) {
+		return append(out, inByte), nil
+	}
+
+	letter := letterIndex(inByte)
+	if letter < 0 {
+		return nil, fmt.Errorf("invalid character %q", inByte)
+	}
+
+	out = append(out, what.separator()...)
+
+	outByte := defs.UpperCase[what.press(letter)]
+	if what.preserveCase && inByte >= 'a' {
+		outByte += 'a' - 'A'
+	}
+
+	what.letters++
+
+	return append(out, outByte), nil
+}
+
+// press steps the rotors and runs the letter through the machine, like a key press.
+func (what *Transformer) press(letter int) int {
+	if what.tracer != nil {
+		trace, out := trace(what.machine, letter)
+		what.tracer(trace)
+
+		return out
+	}
+
+	what.machine.Step()
+	if what.scrambler != nil {
+		return what.scrambler.Transform(what.machine, letter)
+	}
+
+	return what.machine.Transform(letter)
+}
+
+// width returns the number of bytes appendByte writes at most for the input byte.
+func (what *Transformer) width(inByte byte) int {
+	if what.preserveFormatting {
+		return 1
+	}
+
+	return len(what.separator()) + 1
+}
+
+func (what *Transformer) separator() string {
+	if what.preserveFormatting || what.letters == 0 {
+		return ""
+	}
+
+	switch {
+	case what.letters%80 == 0:
+		return " \n"
+
+	case what.letters%5 == 0:
+		return " "
+
+	default:
+		return ""
+	}
+}
+
+func (what *Transformer) shouldEncrypt(c byte) bool {
+	if what.preserveCase {
+		return letterIndex(c) >= 0
+	}
+
+	return c >= 'A' && c <= 'Z'
+}
+
+func letterIndex(c byte) int {
+	switch {
+	case c >= 'A' && c <= 'Z':
+		return int(c - 'A')
+
+	case c >= 'a' && c <= 'z':
+		return int(c - 'a')
+
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,193 @@
+package procedure
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"github.com/r3db34n1an/enigma/pkg/enigma"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"math/rand/v2"
+	"strings"
+)
+
+// DoubledIndicator is the procedure of the german army and air force until May 1940. The operator picks a message key
+// (Spruchschlüssel), types it twice at the Grundstellung and sends the six letters in front of the message, which is
+// encrypted starting at the message key. Until 15 September 1938 the Grundstellung came with the daily key, afterwards
+// the operator chose it for every message and sent it in the clear before the indicator.
+type DoubledIndicator struct {
+	Setting *settings.Setting // daily key, the rotor positions are the Grundstellung of the key sheet
+	engine  *enigma.Enigma
+}
+
+// IndicatorMessage is a message sent with a doubled indicator.
+type IndicatorMessage struct {
+	Grundstellung string // sent in the clear since 15 September 1938, empty before
+	Indicator     string // message key typed twice and encrypted at the Grundstellung
+	Text          string // Spruch, encrypted at the message key
+}
+
+func NewDoubledIndicator(setting *settings.Setting) (*DoubledIndicator, error) {
+	if setting == nil {
+		return nil, fmt.Errorf("no setting")
+	}
+
+	engine, engineError := enigma.NewEnigma(false, false)
+	if engineError != nil {
+		return nil, engineError
+	}
+
+	return &DoubledIndicator{
+		Setting: setting,
+		engine:  engine,
+	}, nil
+}
+
+// Encrypt sends the text with the message key. The Grundstellung of the daily key is used if grundstellung is empty,
+// otherwise it is sent in the clear.
+func (what *DoubledIndicator) Encrypt(plainText []byte, messageKey string, grundstellung string) (*IndicatorMessage, error) {
+	indicator, indicatorError := what.Indicator(messageKey, grundstellung)
+	if indicatorError != nil {
+		return nil, indicatorError
+	}
+
+	cipherText, encryptError := process(what.engine, what.Setting, plainText, messageKey)
+	if encryptError != nil {
+		return nil, encryptError
+	}
+
+	return &IndicatorMessage{
+		Grundstellung: strings.ToUpper(grundstellung),
+		Indicator:     indicator,
+		Text:          cipherText,
+	}, nil
+}
+
+// Decrypt recovers the message key from the indicator and decrypts the text with it.
+func (what *DoubledIndicator) Decrypt(message *IndicatorMessage) ([]byte, string, error) {
+	if message == nil {
+		return nil, "", fmt.Errorf("no message")
+	}
+
+	messageKey, keyError := what.MessageKey(message.Indicator, message.Grundstellung)
+	if keyError != nil {
+		return nil, "", keyError
+	}
+
+	plainText, decryptError := process(what.engine, what.Setting, []byte(message.Text), messageKey)
+	if decryptError != nil {
+		return nil, "", decryptError
+	}
+
+	return []byte(plainText), messageKey, nil
+}
+
+// Indicator types the message key twice at the Grundstellung.
+func (what *DoubledIndicator) Indicator(messageKey string, grundstellung string) (string, error) {
+	session, sessionError := newSession(what.engine, what.Setting, grundstellung)
+	if sessionError != nil {
+		return "", fmt.Errorf("invalid Grundstellung: %v", sessionError)
+	}
+
+	keyError := checkPositions(messageKey, len(what.Setting.Rotors))
+	if keyError != nil {
+		return "", fmt.Errorf("invalid message key: %v", keyError)
+	}
+
+	indicator, encryptError := session.Encrypt([]byte(strings.Repeat(strings.ToUpper(messageKey), 2)))
+	if encryptError != nil {
+		return "", encryptError
+	}
+
+	return removeSpaces(indicator), nil
+}
+
+// Indicators encrypts the message keys of a day's traffic at the same Grundstellung, the material Rejewski worked from.
+func (what *DoubledIndicator) Indicators(messageKeys []string, grundstellung string) ([]string, error) {
+	var indicators []string
+	for _, messageKey := range messageKeys {
+		indicator, indicatorError := what.Indicator(messageKey, grundstellung)
+		if indicatorError != nil {
+			return nil, indicatorError
+		}
+
+		indicators = append(indicators, indicator)
+	}
+
+	return indicators, nil
+}
+
+// MessageKey decrypts the indicator at the Grundstellung, both halves must give the same message key.
+func (what *DoubledIndicator) MessageKey(indicator string, grundstellung string) (string, error) {
+	length := len(what.Setting.Rotors)
+	letters := strings.ToUpper(strings.Join(strings.Fields(indicator), ""))
+	if len(letters) != 2*length {
+		return "", fmt.Errorf("invalid indicator %q, expected %d letters", indicator, 2*length)
+	}
+
+	session, sessionError := newSession(what.engine, what.Setting, grundstellung)
+	if sessionError != nil {
+		return "", fmt.Errorf("invalid Grundstellung: %v", sessionError)
+	}
+
+	doubled, decryptError := session.Decrypt([]byte(letters))
+	if decryptError != nil {
+		return "", fmt.Errorf("invalid indicator %q: %v", indicator, decryptError)
+	}
+
+	doubled = []byte(removeSpaces(doubled))
+	if len(doubled) != 2*length {
+		return "", fmt.Errorf("invalid indicator %q, expected %d letters", indicator, 2*length)
+	}
+
+	if string(doubled[:length]) != string(doubled[length:]) {
+		return "", fmt.Errorf("indicator %q does not repeat the message key: %s", indicator, doubled)
+	}
+
+	return string(doubled[:length]), nil
+}
+
+// RandomMessageKey draws a message key for the setting, the operators were told to avoid anything but this.
+func RandomMessageKey(setting *settings.Setting, random *rand.Rand) string {
+	var builder strings.Builder
+	for range setting.Rotors {
+		builder.WriteByte(defs.UpperCase[random.IntN(len(defs.UpperCase))])
+	}
+
+	return builder.String()
+}
+
+// String formats the message for sending: the Grundstellung if any, the indicator and the text in groups of five.
+func (what *IndicatorMessage) String() string {
+	var parts []string
+	if len(what.Grundstellung) > 0 {
+		parts = append(parts, what.Grundstellung)
+	}
+
+	parts = append(parts, what.Indicator)
+	parts = append(parts, groupLetters(what.Text))
+
+	return strings.Join(parts, " ")
+}
+
+// ParseIndicatorMessage reads a message as formatted by String, rotors is the number of letters of the message key.
+func ParseIndicatorMessage(text string, rotors int, withGrundstellung bool) (*IndicatorMessage, error) {
+	letters := strings.ToUpper(strings.Join(strings.Fields(text), ""))
+
+	message := new(IndicatorMessage)
+	if withGrundstellung {
+		if len(letters) < rotors {
+			return nil, fmt.Errorf("message too short for the Grundstellung")
+		}
+
+		message.Grundstellung = letters[:rotors]
+		letters = letters[rotors:]
+	}
+
+	if len(letters) < 2*rotors {
+		return nil, fmt.Errorf("message too short for the indicator")
+	}
+
+	message.Indicator = letters[:2*rotors]
+	message.Text = letters[2*rotors:]
+
+	return message, nil
+}
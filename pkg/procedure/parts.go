@@ -0,0 +1,140 @@
+package procedure
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DefaultPartLength is the most letters of text sent in one part (Teil), longer messages were split.
+const DefaultPartLength = 250
+
+// EncryptParts splits the text into parts of at most partLength letters, DefaultPartLength if zero. Every part gets
+// its own Grundstellung, message key and Kenngruppe, and a header numbering the parts. A text that fits into one part
+// is sent without numbering.
+func (what *SingleIndicator) EncryptParts(plainText []byte, time string, partLength int) ([]*HeaderMessage, error) {
+	if partLength == 0 {
+		partLength = DefaultPartLength
+	}
+
+	if partLength < 0 {
+		return nil, fmt.Errorf("invalid part length %d", partLength)
+	}
+
+	// the grouping of the input does not count
+	letters := strings.Join(strings.Fields(string(plainText)), "")
+	count := max((len(letters)+partLength-1)/partLength, 1)
+
+	var messages []*HeaderMessage
+	for part := range count {
+		header := Header{
+			Time:          time,
+			Grundstellung: RandomMessageKey(what.Setting, what.Random),
+		}
+
+		if count > 1 {
+			header.Part = part + 1
+			header.Parts = count
+		}
+
+		text := letters[part*partLength : min((part+1)*partLength, len(letters))]
+		message, encryptError := what.encryptPart([]byte(text), header, RandomMessageKey(what.Setting, what.Random))
+		if encryptError != nil {
+			return nil, fmt.Errorf("failed to encrypt part %d: %v", part+1, encryptError)
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// DecryptParts decrypts the parts of a message, received in any order, and joins the text.
+func (what *SingleIndicator) DecryptParts(messages []*HeaderMessage) ([]byte, error) {
+	ordered, orderError := orderParts(messages)
+	if orderError != nil {
+		return nil, orderError
+	}
+
+	var plainText []byte
+	for _, message := range ordered {
+		part, _, decryptError := what.Decrypt(message)
+		if decryptError != nil {
+			return nil, fmt.Errorf("failed to decrypt part %d: %v", message.Header.Part, decryptError)
+		}
+
+		plainText = append(plainText, part...)
+	}
+
+	return plainText, nil
+}
+
+// ReceiveParts finds the day's setting from the Kenngruppe like Receive and decrypts the parts with it.
+func ReceiveParts(messages []*HeaderMessage) ([]byte, error) {
+	ordered, orderError := orderParts(messages)
+	if orderError != nil {
+		return nil, orderError
+	}
+
+	var plainText []byte
+	for _, message := range ordered {
+		part, _, receiveError := Receive(message)
+		if receiveError != nil {
+			return nil, fmt.Errorf("failed to receive part %d: %v", message.Header.Part, receiveError)
+		}
+
+		plainText = append(plainText, part...)
+	}
+
+	return plainText, nil
+}
+
+// orderParts sorts the parts by number and makes sure each one is there exactly once, all sent at the same time.
+func orderParts(messages []*HeaderMessage) ([]*HeaderMessage, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no parts")
+	}
+
+	if slices.Contains(messages, nil) {
+		return nil, fmt.Errorf("nil part")
+	}
+
+	parts := messages[0].Header.Parts
+	if parts == 0 {
+		if len(messages) != 1 {
+			return nil, fmt.Errorf("%d messages without part numbers", len(messages))
+		}
+
+		return messages, nil
+	}
+
+	ordered := make([]*HeaderMessage, parts)
+	for _, message := range messages {
+		header := message.Header
+		if header.Parts != parts {
+			return nil, fmt.Errorf("part %d says %d parts, expected %d", header.Part, header.Parts, parts)
+		}
+
+		if header.Time != messages[0].Header.Time {
+			return nil, fmt.Errorf("part %d was sent at %v, expected %v", header.Part, header.Time, messages[0].Header.Time)
+		}
+
+		if header.Part < 1 || header.Part > parts {
+			return nil, fmt.Errorf("invalid part %d of %d", header.Part, parts)
+		}
+
+		if ordered[header.Part-1] != nil {
+			return nil, fmt.Errorf("duplicate part %d", header.Part)
+		}
+
+		ordered[header.Part-1] = message
+	}
+
+	for index, message := range ordered {
+		if message == nil {
+			return nil, fmt.Errorf("missing part %d of %d", index+1, parts)
+		}
+	}
+
+	return ordered, nil
+}
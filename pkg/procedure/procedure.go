@@ -0,0 +1,71 @@
+// Package procedure implements the ways operators keyed and formatted messages on top of a daily setting.
+package procedure
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"github.com/r3db34n1an/enigma/pkg/enigma"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"strings"
+)
+
+// checkPositions makes sure the value is one letter per rotor.
+func checkPositions(value string, rotors int) error {
+	if len(value) != rotors {
+		return fmt.Errorf("%q has %d letters, expected %d", value, len(value), rotors)
+	}
+
+	for _, letter := range strings.ToUpper(value) {
+		if !strings.ContainsRune(defs.UpperCase, letter) {
+			return fmt.Errorf("invalid letter %q in %q", letter, value)
+		}
+	}
+
+	return nil
+}
+
+// groupLetters splits the letters into groups of five.
+func groupLetters(letters string) string {
+	var groups []string
+	for start := 0; start < len(letters); start += 5 {
+		groups = append(groups, letters[start:min(start+5, len(letters))])
+	}
+
+	return strings.Join(groups, " ")
+}
+
+// newSession returns a machine of the setting at the positions, at those of the setting if positions is empty.
+func newSession(engine *enigma.Enigma, setting *settings.Setting, positions string) (*enigma.Session, error) {
+	session, sessionError := engine.NewSession(setting)
+	if sessionError != nil {
+		return nil, sessionError
+	}
+
+	if len(positions) > 0 {
+		positionsError := session.SetPositions(positions)
+		if positionsError != nil {
+			return nil, positionsError
+		}
+	}
+
+	return session, nil
+}
+
+// process runs the text through the machine starting at the positions, ignoring the grouping.
+func process(engine *enigma.Enigma, setting *settings.Setting, text []byte, positions string) (string, error) {
+	session, sessionError := newSession(engine, setting, positions)
+	if sessionError != nil {
+		return "", sessionError
+	}
+
+	out, processError := session.Encrypt(text)
+	if processError != nil {
+		return "", processError
+	}
+
+	return removeSpaces(out), nil
+}
+
+func removeSpaces(text []byte) string {
+	return strings.Join(strings.Fields(string(text)), "")
+}
@@ -0,0 +1,266 @@
+package procedure
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/enigma"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+const plainText = "ANXGENERALSTABXDIEXTRUPPENSTEHENBEREIT"
+
+// sheetSetting loads the day of the key sheet with the Kenngruppe.
+func sheetSetting(t *testing.T, kenngruppe string) *settings.Setting {
+	var setting settings.Setting
+	assert.Nil(t, setting.Get(kenngruppe))
+
+	return &setting
+}
+
+func TestDoubledIndicator(t *testing.T) {
+	setting := sheetSetting(t, "jkm")
+	procedure, procedureError := NewDoubledIndicator(setting)
+	assert.Nil(t, procedureError)
+
+	engine, engineError := enigma.NewEnigma(false, false)
+	assert.Nil(t, engineError)
+
+	for _, grundstellung := range []string{"", "qrs"} {
+		message, encryptError := procedure.Encrypt([]byte(plainText), "xyz", grundstellung)
+		assert.Nil(t, encryptError)
+		assert.Equal(t, strings.ToUpper(grundstellung), message.Grundstellung)
+		assert.Equal(t, 6, len(message.Indicator))
+		assert.Equal(t, len(plainText), len(message.Text))
+
+		// the indicator is the message key typed twice at the Grundstellung, the text starts at the message key
+		start := setting.Copy()
+		if len(grundstellung) > 0 {
+			for index, rotor := range start.Rotors {
+				rotor.Position = int(strings.ToUpper(grundstellung)[index] - 'A')
+			}
+		}
+
+		indicator, indicatorError := engine.EncryptWithSetting([]byte("XYZXYZ"), start)
+		assert.Nil(t, indicatorError)
+		assert.Equal(t, strings.ReplaceAll(string(indicator), " ", ""), message.Indicator)
+
+		for index, rotor := range start.Rotors {
+			rotor.Position = int("XYZ"[index] - 'A')
+		}
+
+		cipherText, cipherError := engine.EncryptWithSetting([]byte(plainText), start)
+		assert.Nil(t, cipherError)
+		assert.Equal(t, strings.ReplaceAll(string(cipherText), " ", ""), message.Text)
+
+		// sent and received
+		parsed, parseError := ParseIndicatorMessage(message.String(), 3, len(grundstellung) > 0)
+		assert.Nil(t, parseError)
+		assert.Equal(t, message, parsed)
+
+		decrypted, messageKey, decryptError := procedure.Decrypt(parsed)
+		assert.Nil(t, decryptError)
+		assert.Equal(t, "XYZ", messageKey)
+		assert.Equal(t, plainText, string(decrypted))
+	}
+
+	message, encryptError := procedure.Encrypt([]byte("HALLO"), "ABC", "DEF")
+	assert.Nil(t, encryptError)
+	assert.True(t, strings.HasPrefix(message.String(), "DEF "+message.Indicator+" "))
+
+	// a wrong Grundstellung breaks the doubling
+	_, keyError := procedure.MessageKey(message.Indicator, "DEG")
+	assert.NotNil(t, keyError)
+
+	// a day's indicators and random message keys
+	random := rand.New(rand.NewPCG(1938, 1938))
+	var messageKeys []string
+	for range 20 {
+		messageKeys = append(messageKeys, RandomMessageKey(setting, random))
+	}
+
+	indicators, indicatorsError := procedure.Indicators(messageKeys, "")
+	assert.Nil(t, indicatorsError)
+	assert.Equal(t, len(messageKeys), len(indicators))
+	for index, indicator := range indicators {
+		messageKey, messageKeyError := procedure.MessageKey(indicator, "")
+		assert.Nil(t, messageKeyError)
+		assert.Equal(t, messageKeys[index], messageKey)
+	}
+
+	// indicators as written on the message form
+	messageKey, messageKeyError := procedure.MessageKey(strings.ToLower(indicators[0][:3]+" "+indicators[0][3:]), "")
+	assert.Nil(t, messageKeyError)
+	assert.Equal(t, messageKeys[0], messageKey)
+
+	// invalid input
+	_, encryptError = procedure.Encrypt([]byte(plainText), "XY", "")
+	assert.NotNil(t, encryptError)
+
+	_, encryptError = procedure.Encrypt([]byte(plainText), "XYZ", "A1C")
+	assert.NotNil(t, encryptError)
+
+	for _, invalid := range []string{"ABCDE", "      ", "ABC  DE"} {
+		_, keyError = procedure.MessageKey(invalid, "")
+		assert.NotNil(t, keyError, invalid)
+	}
+
+	_, parseError := ParseIndicatorMessage("ABC DE", 3, false)
+	assert.NotNil(t, parseError)
+
+	_, procedureError = NewDoubledIndicator(nil)
+	assert.NotNil(t, procedureError)
+}
+
+func TestSingleIndicator(t *testing.T) {
+	setting := sheetSetting(t, "ino")
+	procedure, procedureError := NewSingleIndicator(setting)
+	assert.Nil(t, procedureError)
+	procedure.Random = rand.New(rand.NewPCG(1940, 1940))
+
+	message, encryptError := procedure.Encrypt([]byte(plainText), "1510")
+	assert.Nil(t, encryptError)
+	assert.Equal(t, "1510", message.Header.Time)
+	assert.Equal(t, 0, message.Header.Parts)
+	assert.Equal(t, KenngruppeLength+len(plainText), message.Header.Letters)
+	assert.Contains(t, setting.IDGroups, strings.ToLower(message.Kenngruppe[2:]))
+
+	// the header carries the encrypted message key, the Kenngruppe stays in the clear
+	sent := message.String()
+	assert.Regexp(t, `^1510 = 43 = [A-Z]{3} [A-Z]{3} =\n`+message.Kenngruppe+` `, sent)
+
+	received, parseError := ParseHeaderMessage(sent)
+	assert.Nil(t, parseError)
+	assert.Equal(t, message, received)
+
+	decrypted, daySetting, receiveError := Receive(received)
+	assert.Nil(t, receiveError)
+	assert.Equal(t, plainText, string(decrypted))
+	assert.Equal(t, setting.IDGroups, daySetting.IDGroups)
+
+	// a known message key
+	message, encryptError = procedure.EncryptWithKey([]byte(plainText), "0915", "ehz", "XWB")
+	assert.Nil(t, encryptError)
+	assert.Equal(t, "EHZ", message.Header.Grundstellung)
+
+	decrypted, messageKey, decryptError := procedure.Decrypt(message)
+	assert.Nil(t, decryptError)
+	assert.Equal(t, "XWB", messageKey)
+	assert.Equal(t, plainText, string(decrypted))
+
+	// headers of messages in parts
+	header := Header{Time: "2220", Part: 1, Parts: 2, Letters: 165, Grundstellung: "EHZ", MessageKey: "TBS"}
+	assert.Equal(t, "2220 = 2TLE 1TL = 165 = EHZ TBS =", header.String())
+
+	parsedHeader, headerError := ParseHeader(" 2220 = 2tle 1tl =  165 = ehz tbs = ")
+	assert.Nil(t, headerError)
+	assert.Equal(t, header, parsedHeader)
+
+	for _, invalid := range []string{"", "2220 = 165 = EHZ =", "2220 = 2TLE 3TL = 165 = EHZ TBS =", "22 = 5 = A B =",
+		"9999 = 165 = EHZ TBS =", "2460 = 165 = EHZ TBS =", "2400 = 165 = EHZ TBS ="} {
+		_, headerError = ParseHeader(invalid)
+		assert.NotNil(t, headerError, invalid)
+	}
+
+	_, parseError = ParseHeaderMessage("1510 = 12 = EHZ TBS =\nABCDE FGHIJ")
+	assert.NotNil(t, parseError)
+
+	// the key sheet is found by the Kenngruppe in either case
+	var byKenngruppe settings.Setting
+	assert.Nil(t, byKenngruppe.Get("UDL"))
+	assert.Equal(t, setting.IDGroups, byKenngruppe.IDGroups)
+	assert.Nil(t, byKenngruppe.Get("udl"))
+	assert.NotNil(t, byKenngruppe.Get("QQQ"))
+
+	_, _, receiveError = Receive(&HeaderMessage{Kenngruppe: "ABQQQ"})
+	assert.NotNil(t, receiveError)
+
+	// invalid input
+	for _, invalidTime := range []string{"915", "9999", "2460", "2400"} {
+		_, encryptError = procedure.EncryptWithKey([]byte(plainText), invalidTime, "EHZ", "XWB")
+		assert.NotNil(t, encryptError, invalidTime)
+	}
+
+	_, encryptError = procedure.EncryptWithKey([]byte(plainText), "2359", "EHZ", "XWB")
+	assert.Nil(t, encryptError)
+
+	_, encryptError = procedure.EncryptWithKey([]byte(plainText), "0915", "EH", "XWB")
+	assert.NotNil(t, encryptError)
+
+	procedure.Setting = setting.Copy()
+	procedure.Setting.IDGroups = nil
+	_, encryptError = procedure.Encrypt([]byte(plainText), "0915")
+	assert.NotNil(t, encryptError)
+}
+
+func TestParts(t *testing.T) {
+	procedure, procedureError := NewSingleIndicator(sheetSetting(t, "upc"))
+	assert.Nil(t, procedureError)
+	procedure.Random = rand.New(rand.NewPCG(250, 250))
+
+	longText := strings.Repeat(plainText, 15)
+	messages, encryptError := procedure.EncryptParts([]byte(longText), "2220", 0)
+	assert.Nil(t, encryptError)
+	assert.Equal(t, 3, len(messages))
+
+	for index, message := range messages {
+		assert.Equal(t, index+1, message.Header.Part)
+		assert.Equal(t, 3, message.Header.Parts)
+		assert.Equal(t, "2220", message.Header.Time)
+		assert.True(t, strings.HasPrefix(message.String(), fmt.Sprintf("2220 = 3TLE %dTL = ", index+1)))
+	}
+
+	assert.Equal(t, DefaultPartLength, len(messages[0].Text))
+	assert.Equal(t, DefaultPartLength, len(messages[1].Text))
+	assert.Equal(t, len(longText)-2*DefaultPartLength, len(messages[2].Text))
+	assert.NotEqual(t, messages[0].Header.MessageKey+messages[0].Header.Grundstellung,
+		messages[1].Header.MessageKey+messages[1].Header.Grundstellung)
+
+	// the parts arrive in any order, over the air
+	var received []*HeaderMessage
+	for _, index := range []int{2, 0, 1} {
+		message, parseError := ParseHeaderMessage(messages[index].String())
+		assert.Nil(t, parseError)
+		received = append(received, message)
+	}
+
+	decrypted, decryptError := procedure.DecryptParts(received)
+	assert.Nil(t, decryptError)
+	assert.Equal(t, longText, string(decrypted))
+
+	decrypted, decryptError = ReceiveParts(received)
+	assert.Nil(t, decryptError)
+	assert.Equal(t, longText, string(decrypted))
+
+	// short texts go in one part without numbering, the grouping does not count
+	messages, encryptError = procedure.EncryptParts([]byte("ANGRIFF IM MORGENGRAUEN"), "0600", 100)
+	assert.Nil(t, encryptError)
+	assert.Equal(t, 1, len(messages))
+	assert.Equal(t, 0, messages[0].Header.Parts)
+
+	decrypted, decryptError = procedure.DecryptParts(messages)
+	assert.Nil(t, decryptError)
+	assert.Equal(t, "ANGRIFFIMMORGENGRAUEN", string(decrypted))
+
+	messages, encryptError = procedure.EncryptParts([]byte("ANGRIFF IM MORGENGRAUEN"), "0600", 7)
+	assert.Nil(t, encryptError)
+	assert.Equal(t, 3, len(messages))
+
+	// incomplete, duplicate and mixed parts
+	for _, invalid := range [][]*HeaderMessage{
+		nil,
+		{messages[0], messages[1]},
+		{messages[0], messages[1], messages[1]},
+		{messages[0], messages[1], messages[2], messages[2]},
+		{messages[0], messages[1], received[0]},
+		{messages[0], nil, messages[2]},
+	} {
+		_, decryptError = procedure.DecryptParts(invalid)
+		assert.NotNil(t, decryptError)
+	}
+
+	_, encryptError = procedure.EncryptParts([]byte(plainText), "0600", -1)
+	assert.NotNil(t, encryptError)
+}
@@ -0,0 +1,259 @@
+package procedure
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"github.com/r3db34n1an/enigma/pkg/enigma"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// KenngruppeLength is the first group of the body: two random letters and a Kenngruppe of the key sheet.
+const KenngruppeLength = 5
+
+// timeExpression matches the Uhrzeit as four digits HHMM, 0000 to 2359.
+const timeExpression = `(?:[01]\d|2[0-3])[0-5]\d`
+
+var (
+	timePattern   = regexp.MustCompile(`^` + timeExpression + `$`)
+	headerPattern = regexp.MustCompile(`^(` + timeExpression + `) = (?:(\d+)TLE (\d+)TL = )?(\d+) = ([A-Z]+) ([A-Z]+) =$`)
+)
+
+// SingleIndicator is the procedure of the german army and air force from May 1940. For every message the operator
+// picks a Grundstellung and a message key (Spruchschlüssel), encrypts the message key once at the Grundstellung and
+// sends both in the clear header. The body starts with a Kenngruppe of the day, unencrypted and padded with two random
+// letters, which tells the receiver which key sheet setting to use.
+type SingleIndicator struct {
+	Setting *settings.Setting // daily key
+	Random  *rand.Rand        // draws Grundstellung, message key and Kenngruppe
+	engine  *enigma.Enigma
+}
+
+// Header is the clear part in front of the body, e.g. "1510 = 2TLE 1TL = 165 = EHZ TBS =".
+type Header struct {
+	Time          string // Uhrzeit, four digits HHMM
+	Part          int    // Teil, 0 for a message in one part
+	Parts         int    // Anzahl der Teile, 0 for a message in one part
+	Letters       int    // Buchstabenzahl of the body, Kenngruppe included
+	Grundstellung string // chosen by the operator
+	MessageKey    string // Spruchschlüssel, encrypted at the Grundstellung
+}
+
+// HeaderMessage is a message sent with the single indicator procedure.
+type HeaderMessage struct {
+	Header     Header
+	Kenngruppe string // first group of the body, in the clear
+	Text       string // Spruch, encrypted at the message key
+}
+
+func NewSingleIndicator(setting *settings.Setting) (*SingleIndicator, error) {
+	if setting == nil {
+		return nil, fmt.Errorf("no setting")
+	}
+
+	engine, engineError := enigma.NewEnigma(false, false)
+	if engineError != nil {
+		return nil, engineError
+	}
+
+	return &SingleIndicator{
+		Setting: setting,
+		Random:  rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())), // #nosec:G404
+		engine:  engine,
+	}, nil
+}
+
+// Encrypt sends the text in one part with a random Grundstellung, message key and Kenngruppe.
+func (what *SingleIndicator) Encrypt(plainText []byte, time string) (*HeaderMessage, error) {
+	return what.EncryptWithKey(plainText, time, RandomMessageKey(what.Setting, what.Random),
+		RandomMessageKey(what.Setting, what.Random))
+}
+
+// EncryptWithKey sends the text in one part with the given Grundstellung and message key.
+func (what *SingleIndicator) EncryptWithKey(plainText []byte, time string, grundstellung string, messageKey string) (*HeaderMessage, error) {
+	return what.encryptPart(plainText, Header{
+		Time:          time,
+		Grundstellung: grundstellung,
+	}, messageKey)
+}
+
+// Decrypt recovers the message key from the header and decrypts the body with the daily key.
+func (what *SingleIndicator) Decrypt(message *HeaderMessage) ([]byte, string, error) {
+	if message == nil {
+		return nil, "", fmt.Errorf("no message")
+	}
+
+	if len(message.Header.MessageKey) != len(what.Setting.Rotors) {
+		return nil, "", fmt.Errorf("invalid message key %q, expected %d letters", message.Header.MessageKey,
+			len(what.Setting.Rotors))
+	}
+
+	grundstellungError := checkPositions(message.Header.Grundstellung, len(what.Setting.Rotors))
+	if grundstellungError != nil {
+		return nil, "", fmt.Errorf("invalid Grundstellung: %v", grundstellungError)
+	}
+
+	encryptedKey := []byte(message.Header.MessageKey)
+	messageKey, keyError := process(what.engine, what.Setting, encryptedKey, message.Header.Grundstellung)
+	if keyError != nil {
+		return nil, "", fmt.Errorf("invalid header: %v", keyError)
+	}
+
+	plainText, decryptError := process(what.engine, what.Setting, []byte(message.Text), messageKey)
+	if decryptError != nil {
+		return nil, "", decryptError
+	}
+
+	return []byte(plainText), messageKey, nil
+}
+
+// Receive finds the day's setting on the key sheet from the Kenngruppe and decrypts the message with it.
+func Receive(message *HeaderMessage) ([]byte, *settings.Setting, error) {
+	if message == nil {
+		return nil, nil, fmt.Errorf("no message")
+	}
+
+	if len(message.Kenngruppe) != KenngruppeLength {
+		return nil, nil, fmt.Errorf("invalid Kenngruppe group %q, expected %d letters", message.Kenngruppe,
+			KenngruppeLength)
+	}
+
+	var setting settings.Setting
+	getError := setting.Get(message.Kenngruppe[2:])
+	if getError != nil {
+		return nil, nil, getError
+	}
+
+	procedure, procedureError := NewSingleIndicator(&setting)
+	if procedureError != nil {
+		return nil, nil, procedureError
+	}
+
+	plainText, _, decryptError := procedure.Decrypt(message)
+	if decryptError != nil {
+		return nil, nil, decryptError
+	}
+
+	return plainText, &setting, nil
+}
+
+// Kenngruppe picks a Kenngruppe of the day and pads it with two random letters.
+func (what *SingleIndicator) Kenngruppe() (string, error) {
+	if len(what.Setting.IDGroups) == 0 {
+		return "", fmt.Errorf("no Kenngruppen in the setting")
+	}
+
+	idGroup := what.Setting.IDGroups[what.Random.IntN(len(what.Setting.IDGroups))]
+	filler := []byte{defs.UpperCase[what.Random.IntN(len(defs.UpperCase))], defs.UpperCase[what.Random.IntN(len(defs.UpperCase))]}
+
+	return string(filler) + strings.ToUpper(idGroup), nil
+}
+
+func (what *SingleIndicator) encryptPart(plainText []byte, header Header, messageKey string) (*HeaderMessage, error) {
+	if !timePattern.MatchString(header.Time) {
+		return nil, fmt.Errorf("invalid time %q, expected HHMM from 0000 to 2359", header.Time)
+	}
+
+	rotors := len(what.Setting.Rotors)
+	grundstellungError := checkPositions(header.Grundstellung, rotors)
+	if grundstellungError != nil {
+		return nil, fmt.Errorf("invalid Grundstellung: %v", grundstellungError)
+	}
+
+	keyError := checkPositions(messageKey, rotors)
+	if keyError != nil {
+		return nil, fmt.Errorf("invalid message key: %v", keyError)
+	}
+
+	kenngruppe, kenngruppeError := what.Kenngruppe()
+	if kenngruppeError != nil {
+		return nil, kenngruppeError
+	}
+
+	header.Grundstellung = strings.ToUpper(header.Grundstellung)
+	encryptedKey, encryptError := process(what.engine, what.Setting, []byte(messageKey), header.Grundstellung)
+	if encryptError != nil {
+		return nil, encryptError
+	}
+
+	cipherText, encryptError := process(what.engine, what.Setting, plainText, messageKey)
+	if encryptError != nil {
+		return nil, encryptError
+	}
+
+	header.MessageKey = encryptedKey
+	header.Letters = len(kenngruppe) + len(cipherText)
+
+	return &HeaderMessage{
+		Header:     header,
+		Kenngruppe: kenngruppe,
+		Text:       cipherText,
+	}, nil
+}
+
+func (what Header) String() string {
+	parts := ""
+	if what.Parts > 0 {
+		parts = fmt.Sprintf("%dTLE %dTL = ", what.Parts, what.Part)
+	}
+
+	return fmt.Sprintf("%v = %v%d = %v %v =", what.Time, parts, what.Letters, what.Grundstellung, what.MessageKey)
+}
+
+// ParseHeader reads a header as formatted by String.
+func ParseHeader(text string) (Header, error) {
+	var header Header
+
+	match := headerPattern.FindStringSubmatch(strings.Join(strings.Fields(strings.ToUpper(text)), " "))
+	if match == nil {
+		return header, fmt.Errorf("invalid header %q", text)
+	}
+
+	header.Time = match[1]
+	if len(match[2]) > 0 {
+		header.Parts, _ = strconv.Atoi(match[2])
+		header.Part, _ = strconv.Atoi(match[3])
+		if header.Part < 1 || header.Part > header.Parts {
+			return header, fmt.Errorf("invalid part %d of %d", header.Part, header.Parts)
+		}
+	}
+
+	header.Letters, _ = strconv.Atoi(match[4])
+	header.Grundstellung = match[5]
+	header.MessageKey = match[6]
+
+	return header, nil
+}
+
+// String formats the message for sending: the header on the first line, then the body in groups of five.
+func (what *HeaderMessage) String() string {
+	return what.Header.String() + "\n" + groupLetters(what.Kenngruppe+what.Text)
+}
+
+// ParseHeaderMessage reads a message as formatted by String and checks the letter count.
+func ParseHeaderMessage(text string) (*HeaderMessage, error) {
+	headerLine, body, _ := strings.Cut(strings.TrimSpace(text), "\n")
+
+	header, headerError := ParseHeader(headerLine)
+	if headerError != nil {
+		return nil, headerError
+	}
+
+	letters := strings.ToUpper(strings.Join(strings.Fields(body), ""))
+	if len(letters) < KenngruppeLength {
+		return nil, fmt.Errorf("message too short for the Kenngruppe")
+	}
+
+	if len(letters) != header.Letters {
+		return nil, fmt.Errorf("message has %d letters, the header says %d", len(letters), header.Letters)
+	}
+
+	return &HeaderMessage{
+		Header:     header,
+		Kenngruppe: letters[:KenngruppeLength],
+		Text:       letters[KenngruppeLength:],
+	}, nil
+}
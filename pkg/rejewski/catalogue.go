@@ -0,0 +1,288 @@
+package rejewski
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"io"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Catalogue is the card index the Poles made with the cyclometer: the characteristic of every rotor order and start
+// position. The ring settings are at A, so a candidate's positions are the rotor offsets of the key, window less ring
+// setting. Turnovers happen where they would at ring settings A, a key whose ring settings move a turnover into or
+// out of the six letters of the indicator is not found, the same blind spot the cyclometer had.
+type Catalogue struct {
+	Reflector string
+	Orders    [][]string // by name
+	entries   map[string][]Candidate
+}
+
+// Candidate is a rotor order and Grundstellung with the characteristic looked up.
+type Candidate struct {
+	Order     []string
+	Reflector string
+	Positions string // Grundstellung at ring settings A
+}
+
+// NewCatalogue runs every order of three different rotors through all start positions, on the number of CPUs. The
+// rotors default to settings.EarlyRotors and the reflector to settings.DefaultReflector, the catalogue had to be made
+// again when it came in.
+func NewCatalogue(rotors []string, reflector string) (*Catalogue, error) {
+	if len(rotors) == 0 {
+		rotors = settings.EarlyRotors
+	}
+
+	if len(reflector) == 0 {
+		reflector = settings.DefaultReflector
+	}
+
+	what := &Catalogue{
+		Reflector: reflector,
+		Orders:    settings.RotorOrders(rotors),
+		entries:   make(map[string][]Candidate),
+	}
+
+	if len(what.Orders) == 0 {
+		return nil, fmt.Errorf("no rotor orders, expected at least three rotors")
+	}
+
+	var lock sync.Mutex
+	var firstError error
+	var waitGroup sync.WaitGroup
+	queue := make(chan []string)
+	for range min(runtime.NumCPU(), len(what.Orders)) {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for order := range queue {
+				found, orderError := characteristics(order, reflector)
+
+				lock.Lock()
+				if orderError != nil && firstError == nil {
+					firstError = orderError
+				}
+
+				for index, characteristic := range found {
+					what.add(characteristic, Candidate{Order: order, Reflector: reflector, Positions: positionLetters(index)})
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for _, order := range what.Orders {
+		queue <- order
+	}
+
+	close(queue)
+	waitGroup.Wait()
+
+	if firstError != nil {
+		return nil, firstError
+	}
+
+	what.sort()
+
+	return what, nil
+}
+
+// ParseCatalogue reads a catalogue as written by Write.
+func ParseCatalogue(data []byte) (*Catalogue, error) {
+	what := &Catalogue{entries: make(map[string][]Candidate)}
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// order, reflector and positions, then the characteristic
+		fields := strings.SplitN(line, " ", 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid catalogue line %d: %q", lineNumber, line)
+		}
+
+		order := strings.Split(fields[0], "-")
+		if len(order) != 3 || len(fields[2]) != 3 || strings.Trim(fields[2], defs.UpperCase) != "" {
+			return nil, fmt.Errorf("invalid catalogue line %d: %q", lineNumber, line)
+		}
+
+		characteristic, characteristicError := ParseCharacteristic(fields[3])
+		if characteristicError != nil {
+			return nil, fmt.Errorf("invalid catalogue line %d: %v", lineNumber, characteristicError)
+		}
+
+		if len(what.Reflector) == 0 {
+			what.Reflector = fields[1]
+		}
+
+		if fields[1] != what.Reflector {
+			return nil, fmt.Errorf("invalid catalogue line %d: reflector %v, expected %v", lineNumber, fields[1], what.Reflector)
+		}
+
+		if !seen[fields[0]] {
+			seen[fields[0]] = true
+			what.Orders = append(what.Orders, order)
+		}
+
+		what.add(characteristic, Candidate{Order: order, Reflector: fields[1], Positions: fields[2]})
+	}
+
+	scanError := scanner.Err()
+	if scanError != nil {
+		return nil, fmt.Errorf("failed to read catalogue: %v", scanError)
+	}
+
+	what.sort()
+
+	return what, nil
+}
+
+// Write writes one line per rotor order and start position, by characteristic.
+func (what *Catalogue) Write(writer io.Writer) error {
+	keys := make([]string, 0, len(what.entries))
+	for characteristic := range what.entries {
+		keys = append(keys, characteristic)
+	}
+
+	slices.Sort(keys)
+
+	buffered := bufio.NewWriter(writer)
+	_, _ = fmt.Fprintln(buffered, "# rotor order, reflector, Grundstellung at ring settings A, characteristic")
+	for _, characteristic := range keys {
+		for _, candidate := range what.entries[characteristic] {
+			_, _ = fmt.Fprintf(buffered, "%v %v %v %v\n", strings.Join(candidate.Order, "-"), candidate.Reflector, candidate.Positions, characteristic)
+		}
+	}
+
+	flushError := buffered.Flush()
+	if flushError != nil {
+		return fmt.Errorf("failed to write catalogue: %v", flushError)
+	}
+
+	return nil
+}
+
+// Lookup returns the rotor orders and start positions with the characteristic.
+func (what *Catalogue) Lookup(characteristic Characteristic) []Candidate {
+	return slices.Clone(what.entries[characteristic.String()])
+}
+
+// Solve computes the characteristic of the indicators and looks it up.
+func (what *Catalogue) Solve(indicators []string) ([]Candidate, Characteristic, error) {
+	products, productsError := ParseProducts(indicators)
+	if productsError != nil {
+		return nil, Characteristic{}, productsError
+	}
+
+	characteristic, characteristicError := products.Characteristic()
+	if characteristicError != nil {
+		return nil, characteristic, characteristicError
+	}
+
+	return what.Lookup(characteristic), characteristic, nil
+}
+
+// Characteristics returns the number of different characteristics, Rejewski counted about 105,000 per reflector.
+func (what *Catalogue) Characteristics() int {
+	return len(what.entries)
+}
+
+// Setting returns the candidate as a setting without plugs.
+func (what Candidate) Setting() settings.ExportSetting {
+	exported := settings.ExportSetting{
+		Machine:   settings.DefaultMachine,
+		Reflector: what.Reflector,
+		PlugBoard: make(settings.ExportPlugBoard),
+	}
+
+	for index, name := range what.Order {
+		exported.Rotors = append(exported.Rotors, settings.ExportRotor{
+			Name:        name,
+			Position:    what.Positions[index : index+1],
+			RingSetting: "A",
+		})
+	}
+
+	return exported
+}
+
+func (what Candidate) String() string {
+	return fmt.Sprintf("%v %v %v", strings.Join(what.Order, "-"), what.Reflector, what.Positions)
+}
+
+func (what *Catalogue) add(characteristic Characteristic, candidate Candidate) {
+	key := characteristic.String()
+	what.entries[key] = append(what.entries[key], candidate)
+}
+
+// sort orders the rotor orders by name and the candidates of every characteristic by rotor order and positions, the
+// workers finish in any order.
+func (what *Catalogue) sort() {
+	slices.SortFunc(what.Orders, func(one []string, two []string) int {
+		return strings.Compare(strings.Join(one, "-"), strings.Join(two, "-"))
+	})
+
+	rank := make(map[string]int)
+	for index, order := range what.Orders {
+		rank[strings.Join(order, "-")] = index
+	}
+
+	for _, candidates := range what.entries {
+		slices.SortFunc(candidates, func(one Candidate, two Candidate) int {
+			orderOne, orderTwo := rank[strings.Join(one.Order, "-")], rank[strings.Join(two.Order, "-")]
+			if orderOne != orderTwo {
+				return orderOne - orderTwo
+			}
+
+			return strings.Compare(one.Positions, two.Positions)
+		})
+	}
+}
+
+// characteristics returns the characteristic of every start position of the rotor order, indexed like
+// positionLetters. The machine steps before every letter, the indicator is typed at the six positions after the start.
+func characteristics(order []string, reflectorName string) ([]Characteristic, error) {
+	setting, settingError := settings.NewBareSetting(order, reflectorName)
+	if settingError != nil {
+		return nil, settingError
+	}
+
+	found := make([]Characteristic, settings.RotorPositions)
+	for position := range settings.RotorPositions {
+		setting.Rotors.SetIndex(position)
+
+		var steps [6]settings.Permutation
+		for index := range steps {
+			setting.Rotors.Move()
+			for in := range steps[index] {
+				steps[index][in] = uint8(setting.Scramble(in))
+			}
+		}
+
+		// the scramblers are involutions, the first letter goes back to the key letter and on to the fourth
+		var products Products
+		for index := range products {
+			for letter := range products[index] {
+				products[index][letter] = steps[index+3][steps[index][letter]]
+			}
+		}
+
+		found[position], _ = products.Characteristic()
+	}
+
+	return found, nil
+}
+
+// positionLetters writes a start position as three letters.
+func positionLetters(position int) string {
+	return string([]byte{defs.UpperCase[position/676], defs.UpperCase[position/26%26], defs.UpperCase[position%26]})
+}
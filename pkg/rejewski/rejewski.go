@@ -0,0 +1,191 @@
+// Package rejewski recovers the rotor order and positions from a day's doubled indicators, the way the Polish Cipher
+// Bureau did from 1934 to 1938.
+package rejewski
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Characteristic is the cycle structure of the permutations AD, BE and CF, the lengths of their cycles from long to
+// short. The plug board does not change it, so it depends on the rotor order and positions alone.
+type Characteristic [3][]int
+
+// Products are the permutations AD, BE and CF: the first letter of an indicator maps to the fourth, the second to the
+// fifth and the third to the sixth. Missing letters map to 255.
+type Products [3]settings.Permutation
+
+// missing marks a letter of a product not seen in the indicators.
+const missing = 255
+
+// ParseProducts builds AD, BE and CF from a day's six letter indicators, all encrypted at the same Grundstellung.
+func ParseProducts(indicators []string) (Products, error) {
+	var products Products
+	for index := range products {
+		for letter := range products[index] {
+			products[index][letter] = missing
+		}
+	}
+
+	for _, indicator := range indicators {
+		indicator = strings.ToUpper(strings.Join(strings.Fields(indicator), ""))
+		if len(indicator) != 6 {
+			return products, fmt.Errorf("invalid indicator %q, expected 6 letters", indicator)
+		}
+
+		for index := range products {
+			from := strings.IndexByte(defs.UpperCase, indicator[index])
+			to := strings.IndexByte(defs.UpperCase, indicator[index+3])
+			if from < 0 || to < 0 {
+				return products, fmt.Errorf("invalid indicator %q", indicator)
+			}
+
+			current := products[index][from]
+			if current != missing && int(current) != to {
+				return products, fmt.Errorf("indicator %q contradicts %c to %c", indicator, defs.UpperCase[from], defs.UpperCase[current])
+			}
+
+			products[index][from] = uint8(to)
+		}
+	}
+
+	return products, nil
+}
+
+// Complete reports whether every letter appeared in every place, only then is the characteristic known.
+func (what *Products) Complete() bool {
+	for index := range what {
+		if !what[index].IsValid() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Missing returns the letters not yet seen in the first, second and third place of the indicators.
+func (what *Products) Missing() [3]string {
+	var letters [3]string
+	for index := range what {
+		for letter, image := range what[index] {
+			if image == missing {
+				letters[index] += string(defs.UpperCase[letter])
+			}
+		}
+	}
+
+	return letters
+}
+
+// Characteristic returns the cycle structure of the complete products.
+func (what *Products) Characteristic() (Characteristic, error) {
+	var characteristic Characteristic
+	if !what.Complete() {
+		return characteristic, fmt.Errorf("incomplete products, missing %v", what.Missing())
+	}
+
+	for index := range what {
+		characteristic[index] = cycleLengths(what[index])
+	}
+
+	return characteristic, nil
+}
+
+// Cycles writes a product as its cycles, like (AQZ)(B)..., the longest first.
+func Cycles(permutation settings.Permutation) string {
+	var cycles []string
+	var seen [len(defs.UpperCase)]bool
+	for start := range permutation {
+		if seen[start] || permutation[start] == missing {
+			continue
+		}
+
+		var cycle strings.Builder
+		for letter := start; !seen[letter] && permutation[letter] != missing; letter = int(permutation[letter]) {
+			seen[letter] = true
+			cycle.WriteByte(defs.UpperCase[letter])
+		}
+
+		cycles = append(cycles, "("+cycle.String()+")")
+	}
+
+	slices.SortStableFunc(cycles, func(one string, two string) int {
+		return len(two) - len(one)
+	})
+
+	return strings.Join(cycles, "")
+}
+
+// ParseCharacteristic reads a characteristic as written by String.
+func ParseCharacteristic(text string) (Characteristic, error) {
+	var characteristic Characteristic
+	parts := strings.Split(text, "/")
+	if len(parts) != len(characteristic) {
+		return characteristic, fmt.Errorf("invalid characteristic %q, expected three parts", text)
+	}
+
+	for index, part := range parts {
+		total := 0
+		for _, field := range strings.Fields(part) {
+			length, lengthError := strconv.Atoi(field)
+			if lengthError != nil || length < 1 {
+				return characteristic, fmt.Errorf("invalid cycle length %q in %q", field, text)
+			}
+
+			characteristic[index] = append(characteristic[index], length)
+			total += length
+		}
+
+		if total != len(defs.UpperCase) {
+			return characteristic, fmt.Errorf("invalid characteristic %q, cycles of %d letters", text, total)
+		}
+
+		slices.SortFunc(characteristic[index], func(one int, two int) int {
+			return two - one
+		})
+	}
+
+	return characteristic, nil
+}
+
+// String writes the cycle lengths of AD, BE and CF, like 13 13 / 10 10 3 3 / 12 12 1 1.
+func (what Characteristic) String() string {
+	parts := make([]string, len(what))
+	for index, lengths := range what {
+		fields := make([]string, len(lengths))
+		for position, length := range lengths {
+			fields[position] = strconv.Itoa(length)
+		}
+
+		parts[index] = strings.Join(fields, " ")
+	}
+
+	return strings.Join(parts, " / ")
+}
+
+// cycleLengths returns the lengths of the cycles of a complete permutation from long to short.
+func cycleLengths(permutation settings.Permutation) []int {
+	var lengths []int
+	var seen [len(defs.UpperCase)]bool
+	for start := range permutation {
+		length := 0
+		for letter := start; !seen[letter]; letter = int(permutation[letter]) {
+			seen[letter] = true
+			length++
+		}
+
+		if length > 0 {
+			lengths = append(lengths, length)
+		}
+	}
+
+	slices.SortFunc(lengths, func(one int, two int) int {
+		return two - one
+	})
+
+	return lengths
+}
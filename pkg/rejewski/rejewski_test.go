@@ -0,0 +1,136 @@
+package rejewski
+
+import (
+	"github.com/r3db34n1an/enigma/pkg/procedure"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// testKey has no turnover while the indicators are typed, at its own ring settings or at A
+const testKey = "{rotors: [{name: II, position: K, ring_setting: D}, {name: III, position: F, ring_setting: B}, {name: I, position: W, ring_setting: C}], reflector: B, plug_board: {A: M, M: A, F: I, I: F, N: V, V: N, P: S, S: P, T: U, U: T, W: Z, Z: W}}"
+
+func testIndicators(t *testing.T, count int) (*settings.Setting, []string) {
+	var setting settings.Setting
+	assert.Nil(t, setting.Parse(testKey))
+
+	doubled, doubledError := procedure.NewDoubledIndicator(&setting)
+	assert.Nil(t, doubledError)
+
+	random := rand.New(rand.NewPCG(1, 2))
+	messageKeys := make([]string, count)
+	for index := range messageKeys {
+		messageKeys[index] = procedure.RandomMessageKey(&setting, random)
+	}
+
+	indicators, indicatorsError := doubled.Indicators(messageKeys, "")
+	assert.Nil(t, indicatorsError)
+
+	return &setting, indicators
+}
+
+func TestCharacteristic(t *testing.T) {
+	_, indicators := testIndicators(t, 200)
+
+	products, productsError := ParseProducts(indicators)
+	assert.Nil(t, productsError)
+	assert.True(t, products.Complete())
+	assert.Equal(t, [3]string{}, products.Missing())
+
+	characteristic, characteristicError := products.Characteristic()
+	assert.Nil(t, characteristicError)
+
+	// the cycles of a product of two involutions without fixed points come in pairs of equal length
+	for index, lengths := range characteristic {
+		sum := 0
+		for _, length := range lengths {
+			sum += length
+		}
+
+		assert.Equal(t, 26, sum)
+		assert.Equal(t, 0, len(lengths)%2, lengths)
+		for pair := 0; pair < len(lengths); pair += 2 {
+			assert.Equal(t, lengths[pair], lengths[pair+1], lengths)
+		}
+
+		assert.Equal(t, 26+len(lengths)*2, len(Cycles(products[index])))
+	}
+
+	parsed, parseError := ParseCharacteristic(characteristic.String())
+	assert.Nil(t, parseError)
+	assert.Equal(t, characteristic, parsed)
+
+	// too few indicators leave letters out
+	few, fewError := ParseProducts(indicators[:5])
+	assert.Nil(t, fewError)
+	assert.False(t, few.Complete())
+	assert.NotEmpty(t, few.Missing()[0])
+	_, characteristicError = few.Characteristic()
+	assert.NotNil(t, characteristicError)
+
+	for _, invalid := range [][]string{{"ABCDE"}, {"ABCDE1"}, {"ABCDEF", "AXXBXX"}} {
+		_, productsError = ParseProducts(invalid)
+		assert.NotNil(t, productsError, invalid)
+	}
+
+	for _, invalid := range []string{"13 13 / 26", "13 13 / 26 / 25", "26 / 26 / X", "26 / 26 / 0 26"} {
+		_, parseError = ParseCharacteristic(invalid)
+		assert.NotNil(t, parseError, invalid)
+	}
+}
+
+func TestCatalogue(t *testing.T) {
+	setting, indicators := testIndicators(t, 200)
+
+	catalogue, catalogueError := NewCatalogue(nil, "")
+	assert.Nil(t, catalogueError)
+	assert.Len(t, catalogue.Orders, 6)
+	assert.Greater(t, catalogue.Characteristics(), 10000)
+
+	candidates, characteristic, solveError := catalogue.Solve(indicators)
+	assert.Nil(t, solveError)
+	assert.NotEmpty(t, candidates)
+
+	// the key is among the candidates, at its rotor offsets
+	var offsets strings.Builder
+	for _, rotor := range setting.Rotors {
+		offsets.WriteByte(byte('A' + (rotor.Position-rotor.RingSetting+26)%26))
+	}
+
+	want := Candidate{Order: []string{"II", "III", "I"}, Reflector: "B", Positions: offsets.String()}
+	assert.Contains(t, candidates, want)
+
+	// with the plugs of the key the candidate reads the message keys off the indicators
+	var found settings.Setting
+	assert.Nil(t, found.Import(want.Setting()))
+	found.PlugBoard = setting.PlugBoard
+	doubled, doubledError := procedure.NewDoubledIndicator(&found)
+	assert.Nil(t, doubledError)
+	for _, indicator := range indicators[:10] {
+		_, keyError := doubled.MessageKey(indicator, "")
+		assert.Nil(t, keyError, indicator)
+	}
+
+	// written and read back
+	var written strings.Builder
+	assert.Nil(t, catalogue.Write(&written))
+	parsed, parseError := ParseCatalogue([]byte(written.String()))
+	assert.Nil(t, parseError)
+	assert.Equal(t, catalogue.Characteristics(), parsed.Characteristics())
+	assert.Equal(t, "B", parsed.Reflector)
+	assert.Equal(t, catalogue.Orders, parsed.Orders)
+	assert.Equal(t, candidates, parsed.Lookup(characteristic))
+
+	_, catalogueError = NewCatalogue([]string{"I", "II"}, "")
+	assert.NotNil(t, catalogueError)
+
+	_, catalogueError = NewCatalogue(nil, "X")
+	assert.NotNil(t, catalogueError)
+
+	for _, invalid := range []string{"I-II B AAA 26 / 26 / 26", "I-II-III B AA 26 / 26 / 26", "I-II-III B AAA 26 / 26", "I-II-III B AAA 26 / 26 / 26\nI-II-III A AAB 26 / 26 / 26"} {
+		_, parseError = ParseCatalogue([]byte(invalid))
+		assert.NotNil(t, parseError, invalid)
+	}
+}
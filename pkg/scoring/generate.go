@@ -0,0 +1,43 @@
+//go:build ignore
+
+// generate trains the embedded n-gram tables from the corpora in pkg/embed/corpus.
+package main
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/scoring"
+	"os"
+)
+
+func main() {
+	for _, name := range []string{"german", "english"} {
+		generateError := generate(name)
+		if generateError != nil {
+			_, _ = fmt.Fprintln(os.Stderr, generateError)
+			os.Exit(1)
+		}
+	}
+}
+
+func generate(name string) error {
+	corpus, openError := os.Open(fmt.Sprintf("../embed/corpus/%v.txt", name))
+	if openError != nil {
+		return fmt.Errorf("failed to open corpus: %v", openError)
+	}
+
+	defer func() { _ = corpus.Close() }()
+
+	language, trainError := scoring.TrainLanguage(name, corpus)
+	if trainError != nil {
+		return trainError
+	}
+
+	table, createError := os.Create(fmt.Sprintf("../embed/config/ngrams-%v.txt", name))
+	if createError != nil {
+		return fmt.Errorf("failed to create table: %v", createError)
+	}
+
+	defer func() { _ = table.Close() }()
+
+	return language.Write(table)
+}
@@ -0,0 +1,196 @@
+package scoring
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/embed"
+	"github.com/r3db34n1an/enigma/pkg/enigma"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:generate go run generate.go
+
+var (
+	german      *Language
+	germanOnce  sync.Once
+	germanError error
+
+	english      *Language
+	englishOnce  sync.Once
+	englishError error
+
+	// umlauts are written out, the machine has no keys for them
+	umlauts = strings.NewReplacer("Ä", "AE", "Ö", "OE", "Ü", "UE", "ß", "SS", "ẞ", "SS")
+)
+
+// Language is a model of a language by the n-grams of a corpus, from unigrams to quadgrams.
+type Language struct {
+	Name   string
+	NGrams [MaxN]*NGrams // by length, NGrams[0] are the unigrams
+}
+
+// German returns the embedded model of German, trained on the military prose in pkg/embed/corpus written the way
+// Enigma.Sanitize writes it: umlauts spelled out, X for full stops, XX for colons, Y for commas and no spaces.
+func German() (*Language, error) {
+	germanOnce.Do(func() {
+		german, germanError = ParseLanguage("german", embed.NGramsGermanTxt)
+	})
+
+	return german, germanError
+}
+
+// English returns the embedded model of English, trained on Newton's Opticks and written like German.
+func English() (*Language, error) {
+	englishOnce.Do(func() {
+		english, englishError = ParseLanguage("english", embed.NGramsEnglishTxt)
+	})
+
+	return english, englishError
+}
+
+// Normalize writes text the way it was typed into the machine, see German.
+func Normalize(text string) string {
+	engine := new(enigma.Enigma)
+	return engine.Sanitize(umlauts.Replace(strings.ToUpper(text)))
+}
+
+// TrainLanguage builds a model from a corpus of plain text, normalized first.
+func TrainLanguage(name string, corpus io.Reader) (*Language, error) {
+	data, readError := io.ReadAll(corpus)
+	if readError != nil {
+		return nil, fmt.Errorf("failed to read corpus: %v", readError)
+	}
+
+	text := Normalize(string(data))
+	language := &Language{Name: name}
+	for n := 1; n <= MaxN; n++ {
+		ngrams, trainError := Train(n, text)
+		if trainError != nil {
+			return nil, fmt.Errorf("failed to train %v: %v", name, trainError)
+		}
+
+		language.NGrams[n-1] = ngrams
+	}
+
+	return language, nil
+}
+
+// ParseLanguage reads a model as written by Write: one n-gram and its count per line, lines starting with # are
+// comments.
+func ParseLanguage(name string, data []byte) (*Language, error) {
+	var counts [MaxN]map[string]int
+	for index := range counts {
+		counts[index] = make(map[string]int)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 || len(fields[0]) > MaxN {
+			return nil, fmt.Errorf("invalid %v n-gram line %d: %q", name, lineNumber, line)
+		}
+
+		count, countError := strconv.Atoi(fields[1])
+		if countError != nil {
+			return nil, fmt.Errorf("invalid %v n-gram count on line %d: %v", name, lineNumber, countError)
+		}
+
+		counts[len(fields[0])-1][fields[0]] = count
+	}
+
+	scanError := scanner.Err()
+	if scanError != nil {
+		return nil, fmt.Errorf("failed to read %v n-grams: %v", name, scanError)
+	}
+
+	language := &Language{Name: name}
+	for index := range counts {
+		ngrams, ngramsError := NewNGrams(index+1, counts[index])
+		if ngramsError != nil {
+			return nil, fmt.Errorf("invalid %v n-grams: %v", name, ngramsError)
+		}
+
+		language.NGrams[index] = ngrams
+	}
+
+	return language, nil
+}
+
+// Write writes the counts of the model, which ParseLanguage reads back.
+func (what *Language) Write(writer io.Writer) error {
+	buffered := bufio.NewWriter(writer)
+	_, _ = fmt.Fprintf(buffered, "# %v n-grams: n-gram and count per line\n", what.Name)
+	for _, ngrams := range what.NGrams {
+		keys := make([]string, 0, len(ngrams.counts))
+		for ngram := range ngrams.counts {
+			keys = append(keys, ngram)
+		}
+
+		slices.Sort(keys)
+		for _, ngram := range keys {
+			_, _ = fmt.Fprintf(buffered, "%v %d\n", ngram, ngrams.counts[ngram])
+		}
+	}
+
+	flushError := buffered.Flush()
+	if flushError != nil {
+		return fmt.Errorf("failed to write %v n-grams: %v", what.Name, flushError)
+	}
+
+	return nil
+}
+
+// Scorers returns the scorers for the hill climbing of an attack: bigrams, trigrams and quadgrams.
+func (what *Language) Scorers() []Scorer {
+	return []Scorer{what.NGrams[1], what.NGrams[2], what.NGrams[3]}
+}
+
+// Score rates the letters by quadgrams.
+func (what *Language) Score(letters []uint8) float64 {
+	return what.NGrams[MaxN-1].Score(letters)
+}
+
+// Fitness is the mean quadgram log probability of the normalized text, comparable between texts of any length.
+// Random letters score about the floor, text of the language much higher.
+func (what *Language) Fitness(text string) float64 {
+	letters := Letters(Normalize(text))
+	if len(letters) < MaxN {
+		return what.NGrams[MaxN-1].Floor
+	}
+
+	return what.Score(letters) / float64(len(letters)-MaxN+1)
+}
+
+// Rank orders the texts by fitness, best first, and returns their indices.
+func (what *Language) Rank(texts []string) []int {
+	fitness := make([]float64, len(texts))
+	indices := make([]int, len(texts))
+	for index, text := range texts {
+		fitness[index] = what.Fitness(text)
+		indices[index] = index
+	}
+
+	slices.SortStableFunc(indices, func(one int, two int) int {
+		switch {
+		case fitness[one] > fitness[two]:
+			return -1
+
+		case fitness[one] < fitness[two]:
+			return 1
+		}
+
+		return 0
+	})
+
+	return indices
+}
@@ -0,0 +1,163 @@
+// Package scoring rates how much letters look like language, to rank candidate decrypts.
+package scoring
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"math"
+	"strings"
+)
+
+// MaxN is the longest n-gram with a table, quadgrams.
+const MaxN = 4
+
+// Scorer rates letters from 0 to 25, higher is more like language.
+type Scorer interface {
+	Score(letters []uint8) float64
+}
+
+// Coincidence scores by the index of coincidence, which needs no language model.
+type Coincidence struct{}
+
+// NGrams are the log10 probabilities of every sequence of N letters, in an array indexed by the letters as a number
+// in base 26. Sequences not seen in training get the floor.
+type NGrams struct {
+	N      int
+	Floor  float64 // log10 probability of unseen n-grams
+	table  []float32
+	counts map[string]int
+}
+
+func (what Coincidence) Score(letters []uint8) float64 {
+	return IndexOfCoincidence(letters)
+}
+
+// IndexOfCoincidence is the chance that two letters drawn from the text are equal, about 0.038 for random letters,
+// 0.066 for English and 0.076 for German.
+func IndexOfCoincidence(letters []uint8) float64 {
+	if len(letters) < 2 {
+		return 0
+	}
+
+	var counts [26]int
+	for _, letter := range letters {
+		counts[letter]++
+	}
+
+	sum := 0
+	for _, count := range counts {
+		sum += count * (count - 1)
+	}
+
+	return float64(sum) / float64(len(letters)*(len(letters)-1))
+}
+
+// Letters converts text to letters from 0 to 25, dropping everything but A to Z in either case.
+func Letters(text string) []uint8 {
+	letters := make([]uint8, 0, len(text))
+	for _, character := range strings.ToUpper(text) {
+		index := strings.IndexRune(defs.UpperCase, character)
+		if index >= 0 {
+			letters = append(letters, uint8(index))
+		}
+	}
+
+	return letters
+}
+
+// NewNGrams builds a table from counts of n-grams, all of the same length.
+func NewNGrams(n int, counts map[string]int) (*NGrams, error) {
+	if n < 1 || n > MaxN {
+		return nil, fmt.Errorf("invalid n-gram length %d, expected 1-%d", n, MaxN)
+	}
+
+	total := 0
+	for ngram, count := range counts {
+		if len(ngram) != n || len(Letters(ngram)) != n {
+			return nil, fmt.Errorf("invalid %d-gram %q", n, ngram)
+		}
+
+		if count < 0 {
+			return nil, fmt.Errorf("invalid count %d of %q", count, ngram)
+		}
+
+		total += count
+	}
+
+	if total == 0 {
+		return nil, fmt.Errorf("no %d-grams", n)
+	}
+
+	ngrams := &NGrams{
+		N:      n,
+		Floor:  math.Log10(0.01 / float64(total)),
+		table:  make([]float32, int(math.Pow(26, float64(n)))),
+		counts: make(map[string]int, len(counts)),
+	}
+
+	for index := range ngrams.table {
+		ngrams.table[index] = float32(ngrams.Floor)
+	}
+
+	for ngram, count := range counts {
+		if count > 0 {
+			ngrams.counts[ngram] = count
+			ngrams.table[ngrams.index(Letters(ngram))] = float32(math.Log10(float64(count) / float64(total)))
+		}
+	}
+
+	return ngrams, nil
+}
+
+// Train counts the n-grams of the text, ignoring everything but letters.
+func Train(n int, text string) (*NGrams, error) {
+	letters := Letters(text)
+	counts := make(map[string]int)
+	for start := 0; start+n <= len(letters); start++ {
+		ngram := make([]byte, n)
+		for offset := range ngram {
+			ngram[offset] = defs.UpperCase[letters[start+offset]]
+		}
+
+		counts[string(ngram)]++
+	}
+
+	return NewNGrams(n, counts)
+}
+
+// Score sums the log probabilities of the n-grams of the letters.
+func (what *NGrams) Score(letters []uint8) float64 {
+	if len(letters) < what.N {
+		return 0
+	}
+
+	modulus := len(what.table) / 26
+	index := what.index(letters[:what.N-1])
+
+	score := 0.0
+	for _, letter := range letters[what.N-1:] {
+		index = index%modulus*26 + int(letter)
+		score += float64(what.table[index])
+	}
+
+	return score
+}
+
+// LogProbability returns the log10 probability of the n-gram.
+func (what *NGrams) LogProbability(ngram string) (float64, error) {
+	letters := Letters(ngram)
+	if len(letters) != what.N || len(ngram) != what.N {
+		return 0, fmt.Errorf("invalid %d-gram %q", what.N, ngram)
+	}
+
+	return float64(what.table[what.index(letters)]), nil
+}
+
+func (what *NGrams) index(letters []uint8) int {
+	index := 0
+	for _, letter := range letters {
+		index = index*26 + int(letter)
+	}
+
+	return index
+}
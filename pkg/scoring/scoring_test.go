@@ -0,0 +1,115 @@
+package scoring
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestIndexOfCoincidence(t *testing.T) {
+	assert.Equal(t, 0.0, IndexOfCoincidence(nil))
+	assert.Equal(t, 1.0, IndexOfCoincidence(Letters("AAAA")))
+	assert.Equal(t, 0.0, IndexOfCoincidence(Letters("ABCD")))
+	assert.InDelta(t, 1.0/26, IndexOfCoincidence(Letters(strings.Repeat("ABCDEFGHIJKLMNOPQRSTUVWXYZ", 100))), 0.001)
+	assert.Equal(t, IndexOfCoincidence(Letters("HALLO")), Coincidence{}.Score(Letters("HALLO")))
+
+	assert.Equal(t, []uint8{7, 0, 11, 11, 14}, Letters("Hal-lo 1"))
+}
+
+func TestNGrams(t *testing.T) {
+	ngrams, trainError := Train(2, "abab ab")
+	assert.Nil(t, trainError)
+	assert.Equal(t, 2, ngrams.N)
+
+	// AB three times, BA twice
+	probability, probabilityError := ngrams.LogProbability("AB")
+	assert.Nil(t, probabilityError)
+	assert.InDelta(t, math.Log10(3.0/5), probability, 1e-6)
+
+	unseen, _ := ngrams.LogProbability("ZZ")
+	assert.InDelta(t, ngrams.Floor, unseen, 1e-6)
+	assert.InDelta(t, math.Log10(0.01/5), ngrams.Floor, 1e-6)
+
+	// the score sums the n-grams of the letters
+	assert.InDelta(t, 2*math.Log10(3.0/5)+math.Log10(2.0/5), ngrams.Score(Letters("ABAB")), 1e-6)
+	assert.Equal(t, 0.0, ngrams.Score(Letters("A")))
+	assert.Greater(t, ngrams.Score(Letters("ABAB")), ngrams.Score(Letters("AZBQ")))
+
+	for n := 1; n <= MaxN; n++ {
+		table, tableError := Train(n, "DIEXTRUPPENXSTEHENXBEREIT")
+		assert.Nil(t, tableError)
+		assert.Greater(t, table.Score(Letters("TRUPPEN")), table.Score(Letters("QXJVWYK")))
+	}
+
+	_, probabilityError = ngrams.LogProbability("ABC")
+	assert.NotNil(t, probabilityError)
+
+	for _, invalid := range []struct {
+		n      int
+		counts map[string]int
+	}{
+		{0, map[string]int{"A": 1}},
+		{MaxN + 1, map[string]int{"ABCDE": 1}},
+		{2, map[string]int{"ABC": 1}},
+		{2, map[string]int{"A1": 1}},
+		{2, map[string]int{"AB": -1}},
+		{2, nil},
+	} {
+		_, newError := NewNGrams(invalid.n, invalid.counts)
+		assert.NotNil(t, newError, invalid.counts)
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	german, germanError := German()
+	assert.Nil(t, germanError)
+	assert.Equal(t, "german", german.Name)
+
+	english, englishError := English()
+	assert.Nil(t, englishError)
+	assert.Equal(t, "english", english.Name)
+
+	// trained on corpora of about 90 and 570 kilobytes, none of the texts below is part of them
+	assert.Greater(t, len(german.NGrams[MaxN-1].counts), 15000)
+	assert.Greater(t, len(english.NGrams[MaxN-1].counts), 20000)
+
+	// full stops, commas, colons and quotes as typed into the machine
+	for _, ngram := range []string{"UNGX", "NYDA", "NXXE", "XJ"} {
+		assert.Positive(t, german.NGrams[len(ngram)-1].counts[ngram], ngram)
+	}
+
+	germanText := "Die feindlichen Truppen haben in der Nacht unsere Stellungen angegriffen. Verstärkung wird erbeten."
+	englishText := "The enemy troops attacked our positions during the night. Reinforcements are requested."
+	randomText := "QXJVW KZPMB YGTFD LHNCR OUEIS AQXJV WKZPM BYGTF DLHNC ROUEI"
+
+	assert.Greater(t, german.Fitness(germanText), german.Fitness(englishText))
+	assert.Greater(t, english.Fitness(englishText), english.Fitness(germanText))
+	assert.Greater(t, german.Fitness(englishText), german.Fitness(randomText))
+	assert.Equal(t, []int{0, 1, 2}, german.Rank([]string{germanText, englishText, randomText}))
+	assert.Equal(t, []int{1, 0, 2}, english.Rank([]string{germanText, englishText, randomText}))
+
+	// the conventions of the machine
+	assert.Equal(t, "VERSTAERKUNGXFUEHRERXXANGRIFFYSOFORT", Normalize("Verstärkung. Führer: Angriff, sofort"))
+	assert.Equal(t, german.NGrams[MaxN-1].Score(Letters("ANGRIFF")), german.Score(Letters("ANGRIFF")))
+	assert.Len(t, german.Scorers(), 3)
+
+	trained, trainError := TrainLanguage("test", strings.NewReader("Die Truppen stehen bereit. Die Truppen greifen an."))
+	assert.Nil(t, trainError)
+
+	var written strings.Builder
+	assert.Nil(t, trained.Write(&written))
+	parsed, parseError := ParseLanguage("test", []byte(written.String()))
+	assert.Nil(t, parseError)
+	for n := range MaxN {
+		assert.Equal(t, trained.NGrams[n].table, parsed.NGrams[n].table)
+	}
+
+	_, trainError = TrainLanguage("empty", strings.NewReader("123"))
+	assert.NotNil(t, trainError)
+
+	for _, invalid := range []string{"AB", "AB X", "ABCDE 1", "A1 2", "A 1\nB C"} {
+		_, parseError = ParseLanguage("invalid", []byte(invalid))
+		assert.NotNil(t, parseError, invalid)
+	}
+}
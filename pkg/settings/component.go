@@ -0,0 +1,7 @@
+package settings
+
+// Component is a part of the machine the signal passes on its way to the reflector and back.
+type Component interface {
+	Encrypt(in int) int // towards the reflector
+	Decrypt(in int) int // back from the reflector
+}
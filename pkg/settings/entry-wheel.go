@@ -0,0 +1,85 @@
+package settings
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"strings"
+)
+
+type EntryWheel struct {
+	Name    string
+	Forward Permutation
+	Reverse Permutation
+}
+
+type EntryWheels map[string]*EntryWheel
+
+func GetEntryWheel(name string) (*EntryWheel, error) {
+	registry, registryError := DefaultRegistry()
+	if registryError != nil {
+		return nil, registryError
+	}
+
+	return registry.GetEntryWheel(name)
+}
+
+func (what *EntryWheel) Encrypt(in int) int {
+	return int(what.Forward[in])
+}
+
+func (what *EntryWheel) Decrypt(in int) int {
+	return int(what.Reverse[in])
+}
+
+func (what *EntryWheel) load(data any) error {
+	castData, ok := data.(string)
+	if !ok {
+		return fmt.Errorf("invalid entry wheel %T, expected string", data)
+	}
+
+	// the keyboard letter at index n of the wiring is connected to contact n
+	reverse, mappingError := ParsePermutation(castData)
+	if mappingError != nil {
+		return fmt.Errorf("invalid entry wheel mapping: %v", mappingError)
+	}
+
+	what.Forward = reverse.Inverse()
+	what.Reverse = reverse
+
+	return nil
+}
+
+func (what *EntryWheels) load(data any) error {
+	castData, ok := data.([]byte)
+	if !ok {
+		return fmt.Errorf("invalid entry wheels data, expected []byte, got %T", data)
+	}
+
+	var items map[string]any
+	parseError := yaml.Unmarshal(castData, &items)
+	if parseError != nil {
+		return fmt.Errorf("failed to parse entry wheels: %v", parseError)
+	}
+
+	return what.loadItems(items)
+}
+
+func (what *EntryWheels) loadItems(items map[string]any) error {
+	newEntryWheels := make(EntryWheels)
+	for entryWheelName, entryWheelValue := range items {
+		entryWheel := &EntryWheel{
+			Name: strings.ToUpper(entryWheelName),
+		}
+
+		loadError := entryWheel.load(entryWheelValue)
+		if loadError != nil {
+			return fmt.Errorf("failed to load entry wheel %q: %v", entryWheelName, loadError)
+		}
+
+		newEntryWheels[entryWheel.Name] = entryWheel
+	}
+
+	*what = newEntryWheels
+
+	return nil
+}
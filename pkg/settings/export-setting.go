@@ -8,9 +8,15 @@ import (
 )
 
 type ExportSetting struct {
-	Rotors    []ExportRotor   `json:"rotors,omitempty"     yaml:"rotors,omitempty"`     // Walzenlage
-	Reflector string          `json:"reflector,omitempty"  yaml:"reflector,omitempty"`  // Reflektor
-	PlugBoard ExportPlugBoard `json:"plug_board,omitempty" yaml:"plug_board,omitempty"` // Steckerverbindungen
+	Machine              string          `json:"machine,omitempty"                yaml:"machine,omitempty"`                // Modell
+	Rotors               []ExportRotor   `json:"rotors,omitempty"                 yaml:"rotors,omitempty"`                 // Walzenlage
+	Reflector            string          `json:"reflector,omitempty"              yaml:"reflector,omitempty"`              // Reflektor
+	ReflectorPosition    string          `json:"reflector_position,omitempty"     yaml:"reflector_position,omitempty"`     // UKW Grundstellung
+	ReflectorRingSetting string          `json:"reflector_ring_setting,omitempty" yaml:"reflector_ring_setting,omitempty"` // UKW Ringstellung
+	ReflectorWiring      string          `json:"reflector_wiring,omitempty"       yaml:"reflector_wiring,omitempty"`       // UKW-D Schaltung
+	ReflectorNotation    string          `json:"reflector_notation,omitempty"     yaml:"reflector_notation,omitempty"`     // bletchley or german
+	PlugBoard            ExportPlugBoard `json:"plug_board,omitempty"             yaml:"plug_board,omitempty"`             // Steckerverbindungen
+	Uhr                  *ExportUhr      `json:"uhr,omitempty"                    yaml:"uhr,omitempty"`                    // Enigma Uhr
 
 	// generated values
 	RotorInfo     []string `json:"rotor_info,omitempty"     yaml:"rotor_info,omitempty"`
@@ -42,22 +48,38 @@ func (what *ExportSetting) Generate() error {
 		what.RotorInfo = append(what.RotorInfo, fmt.Sprintf("%v: %v + %v", what.Rotors[index].Name, what.Rotors[index].Position, what.Rotors[index].RingSetting))
 	}
 
+	var letters []string
+	for key := range what.PlugBoard {
+		letters = append(letters, key)
+	}
+
+	// each pair appears once, named by its first letter, independent of the map order
+	slices.Sort(letters)
 	var keys []string
-	for key, value := range what.PlugBoard {
+	for _, key := range letters {
+		value := what.PlugBoard[key]
 		if key != value && !slices.Contains(keys, key) && !slices.Contains(keys, value) {
 			keys = append(keys, key)
 		}
 	}
 
-	slices.Sort(keys)
 	var plugs []string
 	for _, key := range keys {
 		plugs = append(plugs, fmt.Sprintf("%v%v", key, what.PlugBoard[key]))
 	}
 
 	what.Plugs = strings.Join(plugs, " ")
+	if what.Uhr != nil {
+		what.Plugs = fmt.Sprintf("%v (Uhr %02d)", what.Uhr.Plugs, what.Uhr.Position)
+	}
+
+	reflectorInfo := what.Reflector
+	if len(what.ReflectorPosition) > 0 {
+		reflectorInfo = fmt.Sprintf("%v: %v + %v", what.Reflector, what.ReflectorPosition, what.ReflectorRingSetting)
+	}
+
 	what.RotorSettings = strings.Join(what.RotorInfo, "; ")
-	what.Key = strings.Join([]string{what.Reflector, what.RotorSettings, what.Plugs}, " | ")
+	what.Key = strings.Join([]string{reflectorInfo, what.RotorSettings, what.Plugs}, " | ")
 
 	value, printError := what.Print()
 	if printError != nil {
@@ -0,0 +1,6 @@
+package settings
+
+type ExportUhr struct {
+	Position int    `json:"position"        yaml:"position"`        // Uhrstellung
+	Plugs    string `json:"plugs,omitempty" yaml:"plugs,omitempty"` // red a-plug first in each pair
+}
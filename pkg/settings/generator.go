@@ -0,0 +1,291 @@
+package settings
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+const (
+	DefaultPlugPairs  = 10
+	generatorAttempts = 1000
+)
+
+// Generator draws settings from the whole keyspace of a machine, one per day. With Historical set it follows the rules
+// of the german key sheets: no rotor in the same slot as on the previous day, no plug connecting neighbouring letters,
+// Z and A included as on the round alphabet of the rotors, and no rotor order twice within a month.
+type Generator struct {
+	Machine    string    // Modell, DefaultMachine if empty
+	Reflectors []string  // to choose from, all reflectors of the machine if empty
+	PlugPairs  int       // Steckerverbindungen, ignored for machines without plug board
+	Uhr        bool      // plug UhrPairs pairs into the Enigma Uhr at a random Uhrstellung instead of PlugPairs
+	Historical bool      // follow the rules of the key sheets
+	Registry   *Registry // components to choose from, the built-ins if nil
+	random     *rand.Rand
+	previous   *Setting        // setting of the previous day
+	orders     [][]string      // rotor orders used this month
+	idGroups   map[string]bool // Kenngruppen used this month
+}
+
+// NewGenerator returns a generator which draws the same settings for the same seed.
+func NewGenerator(seed uint64) *Generator {
+	return &Generator{
+		Machine:   DefaultMachine,
+		PlugPairs: DefaultPlugPairs,
+		random:    rand.New(rand.NewPCG(seed, seed)), // #nosec:G404
+	}
+}
+
+func NewRandomGenerator() *Generator {
+	return NewGenerator(rand.Uint64()) // #nosec:G404
+}
+
+// NewMonth forgets the rotor orders and Kenngruppen used so far, the previous day still counts.
+func (what *Generator) NewMonth() {
+	what.orders = nil
+	what.idGroups = nil
+}
+
+// Month draws a key sheet for a month of the given number of days. The settings are numbered by day and sorted like a
+// printed sheet, the last day first, so the used days could be cut off.
+func (what *Generator) Month(days int) (Settings, error) {
+	if days < 1 || days > 31 {
+		return nil, fmt.Errorf("invalid number of days %d, expected 1-31", days)
+	}
+
+	what.NewMonth()
+
+	month := make(Settings, days)
+	for day := 1; day <= days; day++ {
+		setting, generateError := what.Next()
+		if generateError != nil {
+			return nil, fmt.Errorf("failed to generate day %d: %v", day, generateError)
+		}
+
+		setting.Day = day
+		month[days-day] = *setting
+	}
+
+	return month, nil
+}
+
+// Next returns the setting of the next day.
+func (what *Generator) Next() (*Setting, error) {
+	registry := what.Registry
+	if registry == nil {
+		defaultRegistry, registryError := DefaultRegistry()
+		if registryError != nil {
+			return nil, registryError
+		}
+
+		registry = defaultRegistry
+	}
+
+	machineName := what.Machine
+	if machineName == "" {
+		machineName = DefaultMachine
+	}
+
+	machine, machineError := registry.GetMachine(machineName)
+	if machineError != nil {
+		return nil, fmt.Errorf("invalid machine %q: %v", machineName, machineError)
+	}
+
+	order, orderError := what.rotorOrder(machine)
+	if orderError != nil {
+		return nil, orderError
+	}
+
+	exported := ExportSetting{
+		Machine: machine.Name,
+	}
+
+	for _, rotorName := range order {
+		exported.Rotors = append(exported.Rotors, ExportRotor{
+			Name:        rotorName,
+			Position:    what.letter(),
+			RingSetting: what.letter(),
+		})
+	}
+
+	reflectorError := what.reflector(registry, machine, &exported)
+	if reflectorError != nil {
+		return nil, reflectorError
+	}
+
+	switch {
+	case machine.PlugBoard && what.Uhr:
+		pairs, pairsError := what.plugPairs(UhrPairs)
+		if pairsError != nil {
+			return nil, pairsError
+		}
+
+		exported.Uhr = &ExportUhr{
+			Position: what.random.IntN(UhrPositions),
+			Plugs:    strings.Join(pairs, " "),
+		}
+
+	case machine.PlugBoard:
+		pairs, pairsError := what.plugPairs(what.PlugPairs)
+		if pairsError != nil {
+			return nil, pairsError
+		}
+
+		exported.PlugBoard = make(ExportPlugBoard)
+		for _, pair := range pairs {
+			exported.PlugBoard[pair[:1]] = pair[1:]
+		}
+	}
+
+	setting := &Setting{
+		Registry: what.Registry,
+	}
+
+	importError := setting.Import(exported)
+	if importError != nil {
+		return nil, fmt.Errorf("invalid generated setting: %v", importError)
+	}
+
+	// Kenngruppen are unique within a month, the receiver finds the day by them
+	if what.idGroups == nil {
+		what.idGroups = make(map[string]bool)
+	}
+
+	for len(setting.IDGroups) < 4 {
+		idGroup := strings.ToLower(what.letter() + what.letter() + what.letter())
+		if what.idGroups[idGroup] {
+			continue
+		}
+
+		what.idGroups[idGroup] = true
+		setting.IDGroups = append(setting.IDGroups, idGroup)
+	}
+
+	what.previous = setting
+	what.orders = append(what.orders, order)
+
+	return setting.Copy(), nil
+}
+
+func (what *Generator) rotorOrder(machine *Machine) ([]string, error) {
+	for range generatorAttempts {
+		var order []string
+		for index, slot := range machine.Slots {
+			var candidates []string
+			for _, rotorName := range slot.Rotors {
+				if slices.Contains(order, rotorName) {
+					continue
+				}
+
+				if what.Historical && !slot.Fixed && what.previous != nil && index < len(what.previous.Rotors) &&
+					what.previous.Rotors[index].Name == rotorName {
+					continue
+				}
+
+				candidates = append(candidates, rotorName)
+			}
+
+			if len(candidates) == 0 {
+				break
+			}
+
+			order = append(order, candidates[what.random.IntN(len(candidates))])
+		}
+
+		if len(order) != len(machine.Slots) {
+			continue
+		}
+
+		if what.Historical && slices.ContainsFunc(what.orders, func(used []string) bool { return slices.Equal(used, order) }) {
+			continue
+		}
+
+		return order, nil
+	}
+
+	return nil, fmt.Errorf("no rotor order left for machine %q", machine.Name)
+}
+
+func (what *Generator) reflector(registry *Registry, machine *Machine, exported *ExportSetting) error {
+	reflectorNames := what.Reflectors
+	if len(reflectorNames) == 0 {
+		reflectorNames = machine.Reflectors
+	}
+
+	if len(reflectorNames) == 0 {
+		return fmt.Errorf("no reflector for machine %q", machine.Name)
+	}
+
+	reflector, reflectorError := registry.GetReflector(reflectorNames[what.random.IntN(len(reflectorNames))])
+	if reflectorError != nil {
+		return reflectorError
+	}
+
+	exported.Reflector = reflector.Name
+	if reflector.Settable {
+		exported.ReflectorPosition = what.letter()
+		exported.ReflectorRingSetting = what.letter()
+	}
+
+	if reflector.Rewirable {
+		// pair up the letters besides the fixed pair
+		var letters []byte
+		for index := range defs.UpperCase {
+			if !strings.ContainsRune(reflector.Fixed, rune(defs.UpperCase[index])) {
+				letters = append(letters, defs.UpperCase[index])
+			}
+		}
+
+		what.random.Shuffle(len(letters), func(one int, two int) {
+			letters[one], letters[two] = letters[two], letters[one]
+		})
+
+		var pairs []string
+		for index := 0; index+1 < len(letters); index += 2 {
+			pairs = append(pairs, string(letters[index:index+2]))
+		}
+
+		exported.ReflectorWiring = strings.Join(pairs, " ")
+	}
+
+	return nil
+}
+
+// plugPairs draws the plug pairs in plugging order, with the Uhr the first letter of a pair takes the red plug.
+func (what *Generator) plugPairs(count int) ([]string, error) {
+	if count < 0 || count > len(defs.UpperCase)/2 {
+		return nil, fmt.Errorf("invalid plug pairs %d, expected 0-%d", count, len(defs.UpperCase)/2)
+	}
+
+	for range generatorAttempts {
+		letters := what.random.Perm(len(defs.UpperCase))
+
+		var pairs []string
+		for index := 0; index < 2*count; index += 2 {
+			one, two := letters[index], letters[index+1]
+			if what.Historical && neighbours(one, two) {
+				break
+			}
+
+			pairs = append(pairs, string([]byte{defs.UpperCase[one], defs.UpperCase[two]}))
+		}
+
+		if len(pairs) == count {
+			return pairs, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no plug board with %d pairs found", count)
+}
+
+func (what *Generator) letter() string {
+	return string(defs.UpperCase[what.random.IntN(len(defs.UpperCase))])
+}
+
+// neighbours reports whether two letters are next to each other in the alphabet, Z and A included.
+func neighbours(one int, two int) bool {
+	distance := (one - two + len(defs.UpperCase)) % len(defs.UpperCase)
+	return distance == 1 || distance == len(defs.UpperCase)-1
+}
@@ -0,0 +1,211 @@
+package settings
+
+import (
+	"encoding/csv"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"html/template"
+	"io"
+	"slices"
+	"strings"
+)
+
+// sheetColumns are the column headers of the printed key sheets.
+var sheetColumns = []string{"Datum", "Walzenlage", "Ringstellung", "Steckerverbindungen", "Kenngruppen"}
+
+// sheetTemplate prints a key sheet on one page, one row per day.
+var sheetTemplate = template.Must(template.New("sheet").Parse(`<!DOCTYPE html>
+<html lang="de">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: "Courier New", monospace; margin: 1cm; }
+h1 { font-size: 14pt; text-align: center; }
+table { border-collapse: collapse; margin: 0 auto; }
+th, td { border: 1px solid black; padding: 2px 8px; white-space: nowrap; }
+th { font-size: 9pt; }
+td { font-size: 11pt; }
+@media print { body { margin: 0; } @page { size: A4 landscape; margin: 1cm; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Rows}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// sheetSetting is a day in the settings.yaml schema, with the ring settings as numbers.
+type sheetSetting struct {
+	Day                  int              `yaml:"day"`
+	IDGroups             []string         `yaml:"id_groups,omitempty,flow"`
+	Machine              string           `yaml:"machine,omitempty"`
+	Rotors               []map[string]int `yaml:"rotors"`
+	Reflector            string           `yaml:"reflector"`
+	ReflectorPosition    string           `yaml:"reflector_position,omitempty"`
+	ReflectorRingSetting string           `yaml:"reflector_ring_setting,omitempty"`
+	ReflectorWiring      string           `yaml:"reflector_wiring,omitempty"`
+	PlugBoard            string           `yaml:"plug_board,omitempty"`
+	Uhr                  *int             `yaml:"uhr,omitempty"`
+}
+
+// SheetColumns returns the columns of the day on a printed key sheet: date, rotor order, ring settings as numbers,
+// plugs followed by the Uhrstellung, if any, and Kenngruppen.
+func (what *Setting) SheetColumns() []string {
+	var rotors, rings []string
+	for _, rotor := range what.Rotors {
+		rotors = append(rotors, rotor.Name)
+		rings = append(rings, fmt.Sprintf("%02d", rotor.RingSetting+1))
+	}
+
+	return []string{
+		fmt.Sprintf("St %2d.", what.Day),
+		strings.Join(rotors, " "),
+		strings.Join(rings, " "),
+		what.sheetPlugs(),
+		strings.Join(what.IDGroups, " "),
+	}
+}
+
+// sheetPlugs returns the plugs as printed on a key sheet, with the Uhr e.g. "KL IT FQ HY XC NP VZ JB SE OG Uhr 27".
+func (what *Setting) sheetPlugs() string {
+	plugs := strings.Join(what.PlugBoard.Plugs(), " ")
+	if what.PlugBoard.Uhr != nil {
+		plugs += fmt.Sprintf(" Uhr %02d", what.PlugBoard.Uhr.Position)
+	}
+
+	return plugs
+}
+
+// SheetLine formats the day like a line of settings-oct_1944.txt, which ParseSheet reads back.
+func (what *Setting) SheetLine() string {
+	columns := what.SheetColumns()
+
+	return fmt.Sprintf("%v  %-10v  %-8v  %-29v  %v", columns[0], columns[1], columns[2], columns[3], columns[4])
+}
+
+// Sheet formats the days in the tabular layout of settings-oct_1944.txt, under the title and the column headers. The
+// machine and the reflector are not printed, like on the army sheets.
+func (what Settings) Sheet(title string) string {
+	var sheet strings.Builder
+	if len(title) > 0 {
+		sheet.WriteString(title + "\n\n")
+	}
+
+	sheet.WriteString(fmt.Sprintf("%-6v  %-10v  %-8v  %-29v  %v\n", sheetColumns[0], sheetColumns[1], sheetColumns[2],
+		sheetColumns[3], sheetColumns[4]))
+	for index := range what {
+		sheet.WriteString(what[index].SheetLine() + "\n")
+	}
+
+	return sheet.String()
+}
+
+// CSV writes the days as comma separated values with a header row.
+func (what Settings) CSV(writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+
+	writeError := csvWriter.Write(sheetColumns)
+	if writeError != nil {
+		return fmt.Errorf("failed to write key sheet: %v", writeError)
+	}
+
+	for index := range what {
+		columns := what[index].SheetColumns()
+		columns[0] = fmt.Sprint(what[index].Day)
+
+		writeError = csvWriter.Write(columns)
+		if writeError != nil {
+			return fmt.Errorf("failed to write key sheet: %v", writeError)
+		}
+	}
+
+	csvWriter.Flush()
+	flushError := csvWriter.Error()
+	if flushError != nil {
+		return fmt.Errorf("failed to write key sheet: %v", flushError)
+	}
+
+	return nil
+}
+
+// HTML writes the days as a printable page, the last day first like on the printed sheets, whatever the order given.
+func (what Settings) HTML(writer io.Writer, title string) error {
+	days := slices.Clone(what)
+	slices.SortStableFunc(days, func(one Setting, two Setting) int {
+		return two.Day - one.Day
+	})
+
+	var rows [][]string
+	for index := range days {
+		rows = append(rows, days[index].SheetColumns())
+	}
+
+	executeError := sheetTemplate.Execute(writer, map[string]any{
+		"Title":   title,
+		"Columns": sheetColumns,
+		"Rows":    rows,
+	})
+	if executeError != nil {
+		return fmt.Errorf("failed to write key sheet: %v", executeError)
+	}
+
+	return nil
+}
+
+// YAML formats the days in the schema of settings.yaml, which Load reads back. Like on a key sheet the rotor positions
+// are left out, the operator picks them for every message.
+func (what Settings) YAML() ([]byte, error) {
+	var days []sheetSetting
+	for index := range what {
+		setting := &what[index]
+		exported := setting.Export()
+
+		day := sheetSetting{
+			Day:                  setting.Day,
+			IDGroups:             setting.IDGroups,
+			Reflector:            exported.Reflector,
+			ReflectorPosition:    exported.ReflectorPosition,
+			ReflectorRingSetting: exported.ReflectorRingSetting,
+			ReflectorWiring:      exported.ReflectorWiring,
+			PlugBoard:            strings.Join(setting.PlugBoard.Plugs(), " "),
+		}
+
+		if setting.PlugBoard.Uhr != nil {
+			position := setting.PlugBoard.Uhr.Position
+			day.Uhr = &position
+		}
+
+		if setting.Machine != nil && setting.Machine.Name != DefaultMachine {
+			day.Machine = setting.Machine.Name
+		}
+
+		for _, rotor := range setting.Rotors {
+			day.Rotors = append(day.Rotors, map[string]int{rotor.Name: rotor.RingSetting + 1})
+		}
+
+		days = append(days, day)
+	}
+
+	var data strings.Builder
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+
+	encodeError := encoder.Encode(days)
+	if encodeError != nil {
+		return nil, fmt.Errorf("failed to marshal settings: %v", encodeError)
+	}
+
+	closeError := encoder.Close()
+	if closeError != nil {
+		return nil, fmt.Errorf("failed to marshal settings: %v", closeError)
+	}
+
+	return []byte(data.String()), nil
+}
@@ -0,0 +1,133 @@
+package settings
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SheetReflector is the reflector of the army key sheets, which do not name it.
+const SheetReflector = "B"
+
+// sheetDayPattern matches the date column of a key sheet line, e.g. "St 31." or "St  4.", indented or not.
+var sheetDayPattern = regexp.MustCompile(`^\s*St\s+(\d{1,2})\.\s`)
+
+// ParseSheet reads a key sheet in the tabular layout of settings-oct_1944.txt, one line per day:
+//
+//	St 31.   IV V I   21 15 16   KL IT FQ HY XC NP VZ JB SE OG   jkm ogi ncj glp
+//
+// with the date, the Walzenlage, the Ringstellung as numbers, the Steckerverbindungen and the Kenngruppen. With the
+// Enigma Uhr the Steckerverbindungen are followed by the Uhrstellung, e.g. "Uhr 27". Lines not starting with a date are
+// titles and column headers and skipped. The days keep the order of the sheet.
+func (what *Settings) ParseSheet(data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		match := sheetDayPattern.FindStringSubmatch(line + " ")
+		if match == nil {
+			continue
+		}
+
+		day, _ := strconv.Atoi(match[1])
+		setting := new(Setting)
+		parseError := setting.parseSheetLine(day, strings.Fields(line)[2:])
+		if parseError != nil {
+			return fmt.Errorf("invalid key sheet line %d: %v", lineNumber, parseError)
+		}
+
+		*what = append(*what, *setting)
+	}
+
+	scanError := scanner.Err()
+	if scanError != nil {
+		return fmt.Errorf("failed to read key sheet: %v", scanError)
+	}
+
+	if len(*what) == 0 {
+		return fmt.Errorf("no days in key sheet")
+	}
+
+	return nil
+}
+
+// GetDay returns a copy of the setting of the day.
+func (what Settings) GetDay(day int) (*Setting, error) {
+	for index := range what {
+		if what[index].Day == day {
+			return what[index].Copy(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("setting for day %d not found", day)
+}
+
+// parseSheetLine reads the columns after the date: rotors up to the first number, as many ring settings as rotors,
+// two-letter plugs, the Uhrstellung and lower case Kenngruppen. Plugs like "VI" look like rotor names, so the columns go by position.
+func (what *Setting) parseSheetLine(day int, fields []string) error {
+	var rotors []any
+	for len(fields) > 0 && !unicode.IsDigit(rune(fields[0][0])) {
+		rotors = append(rotors, fields[0])
+		fields = fields[1:]
+	}
+
+	if len(fields) < len(rotors) {
+		return fmt.Errorf("expected %d ring settings", len(rotors))
+	}
+
+	for index, rotorName := range rotors {
+		ringSetting, ringError := strconv.Atoi(fields[index])
+		if ringError != nil || ringSetting < 1 || ringSetting > 26 {
+			return fmt.Errorf("invalid ring setting %q, expected 01-26", fields[index])
+		}
+
+		rotors[index] = map[string]any{rotorName.(string): ringSetting}
+	}
+
+	fields = fields[len(rotors):]
+
+	var plugs []string
+	for len(fields) > 0 && strings.ToUpper(fields[0]) == fields[0] {
+		plugs = append(plugs, fields[0])
+		fields = fields[1:]
+	}
+
+	var uhrPosition any
+	if len(fields) > 1 && fields[0] == "Uhr" {
+		position, positionError := strconv.Atoi(fields[1])
+		if positionError != nil {
+			return fmt.Errorf("invalid uhr position %q", fields[1])
+		}
+
+		uhrPosition = position
+		fields = fields[2:]
+	}
+
+	var idGroups []any
+	for _, idGroup := range fields {
+		idGroups = append(idGroups, idGroup)
+	}
+
+	data := map[string]any{
+		"day":       day,
+		"rotors":    rotors,
+		"reflector": SheetReflector,
+	}
+
+	if len(plugs) > 0 {
+		data["plug_board"] = strings.Join(plugs, " ")
+	}
+
+	if uhrPosition != nil {
+		data["uhr"] = uhrPosition
+	}
+
+	if len(idGroups) > 0 {
+		data["id_groups"] = idGroups
+	}
+
+	return what.Load(data)
+}
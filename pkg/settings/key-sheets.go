@@ -0,0 +1,318 @@
+package settings
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/embed"
+	"gopkg.in/yaml.v3"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	keySheets      KeySheets
+	keySheetsOnce  sync.Once
+	keySheetsError error
+
+	// germanMonths are the month names printed on the sheets
+	germanMonths = []string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September",
+		"Oktober", "November", "Dezember"}
+
+	sheetNamePattern  = regexp.MustCompile(`^\s*(\S.*?)\s+Nr\.\s*(\d+)\s*$`)
+	sheetMonthPattern = regexp.MustCompile(`^\s*(?:für\s+)?(\pL+)\s+(\d{4})\s*$`)
+)
+
+// KeySheet is the printed key sheet (Schlüsseltafel) of a network for a month, e.g. "Armee-Stabs-Maschinenschlüssel
+// Nr. 28 für Oktober 1944".
+type KeySheet struct {
+	Network string     // Schlüsselbereich
+	Number  int        // Nr., 0 if unknown
+	Month   time.Month // Monat, 0 if unknown
+	Year    int        // Jahr, 0 if unknown
+	Days    Settings   // one setting per day, numbered by Day
+}
+
+type KeySheets []*KeySheet
+
+// GetKeySheets returns the embedded key sheet. The days are the ones of GetSettings, the title comes from the
+// transcribed sheet.
+func GetKeySheets() (KeySheets, error) {
+	keySheetsOnce.Do(func() {
+		allSettings, settingsError := GetSettings()
+		if settingsError != nil {
+			keySheetsError = settingsError
+			return
+		}
+
+		sheet := &KeySheet{}
+		sheet.parseTitle(embed.SettingsOct1944Txt)
+		sheet.Days = allSettings
+
+		keySheets = KeySheets{sheet}
+	})
+
+	return keySheets, keySheetsError
+}
+
+// LoadKeySheet reads a key sheet file, in the tabular layout of settings-oct_1944.txt or as YAML.
+func LoadKeySheet(path string) (*KeySheet, error) {
+	data, readError := os.ReadFile(path) // #nosec:G304
+	if readError != nil {
+		return nil, fmt.Errorf("failed to read %q: %v", path, readError)
+	}
+
+	sheet := new(KeySheet)
+	parseError := sheet.Parse(data)
+	if parseError != nil {
+		return nil, fmt.Errorf("invalid key sheet %q: %v", path, parseError)
+	}
+
+	return sheet, nil
+}
+
+// Name returns the network and the number of the sheet, e.g. "Armee-Stabs-Maschinenschlüssel Nr. 28".
+func (what *KeySheet) Name() string {
+	if what.Number == 0 {
+		return what.Network
+	}
+
+	return strings.TrimSpace(fmt.Sprintf("%v Nr. %d", what.Network, what.Number))
+}
+
+// Title returns the heading of the printed sheet, which Parse reads back.
+func (what *KeySheet) Title() string {
+	title := what.Name()
+	if what.Month != 0 && what.Year != 0 {
+		title += fmt.Sprintf("\nfür %v %d", germanMonths[what.Month-1], what.Year)
+	}
+
+	return title
+}
+
+// Covers reports whether the sheet is valid on the date.
+func (what *KeySheet) Covers(date time.Time) bool {
+	return what.Year == date.Year() && what.Month == date.Month()
+}
+
+// ForDate returns a copy of the setting of the date.
+func (what *KeySheet) ForDate(date time.Time) (*Setting, error) {
+	if !what.Covers(date) {
+		return nil, fmt.Errorf("key sheet %q is not valid on %v", what.Name(), date.Format(time.DateOnly))
+	}
+
+	return what.Days.GetDay(date.Day())
+}
+
+// Parse reads a key sheet in the tabular layout, with the title above the days, or as YAML. The YAML is either a
+// list of days like settings.yaml or a map with the keys network, number, month, year and days.
+func (what *KeySheet) Parse(data []byte) error {
+	var items any
+	yamlError := yaml.Unmarshal(data, &items)
+
+	var parseError error
+	switch castItems := items.(type) {
+	case []any:
+		parseError = what.Days.loadItems(castItems)
+
+	case map[string]any:
+		parseError = what.load(castItems)
+
+	default:
+		// the tabular layout is no YAML or reads as a single string
+		what.parseTitle(data)
+		parseError = what.Days.ParseSheet(data)
+		if parseError != nil && yamlError != nil {
+			parseError = fmt.Errorf("%v, and no YAML: %v", parseError, yamlError)
+		}
+	}
+
+	if parseError != nil {
+		return parseError
+	}
+
+	return what.validate()
+}
+
+// parseTitle takes the network, number, month and year from the lines above the days, where given.
+func (what *KeySheet) parseTitle(data []byte) {
+	for _, line := range strings.Split(string(data), "\n") {
+		if sheetDayPattern.MatchString(line + " ") {
+			return
+		}
+
+		if match := sheetNamePattern.FindStringSubmatch(line); match != nil {
+			what.Network = match[1]
+			what.Number, _ = strconv.Atoi(match[2])
+			continue
+		}
+
+		if match := sheetMonthPattern.FindStringSubmatch(line); match != nil {
+			month, monthError := parseMonth(match[1])
+			if monthError == nil {
+				what.Month = month
+				what.Year, _ = strconv.Atoi(match[2])
+			}
+		}
+	}
+}
+
+func (what *KeySheet) load(data map[string]any) error {
+	for key, value := range data {
+		switch strings.ToLower(key) {
+		case "network":
+			network, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("invalid network %T, expected string", value)
+			}
+
+			what.Network = network
+
+		case "number":
+			number, ok := value.(int)
+			if !ok || number < 0 {
+				return fmt.Errorf("invalid number %v", value)
+			}
+
+			what.Number = number
+
+		case "month":
+			month, monthError := parseMonth(fmt.Sprint(value))
+			if monthError != nil {
+				return monthError
+			}
+
+			what.Month = month
+
+		case "year":
+			year, ok := value.(int)
+			if !ok || year < 1 {
+				return fmt.Errorf("invalid year %v", value)
+			}
+
+			what.Year = year
+
+		case "days":
+			days, ok := value.([]any)
+			if !ok {
+				return fmt.Errorf("invalid days %T, expected a list", value)
+			}
+
+			loadError := what.Days.loadItems(days)
+			if loadError != nil {
+				return loadError
+			}
+
+		default:
+			return fmt.Errorf("invalid key sheet key %q", key)
+		}
+	}
+
+	if len(what.Days) == 0 {
+		return fmt.Errorf("no days in key sheet")
+	}
+
+	return nil
+}
+
+// validate makes sure every day exists in the month and is on the sheet only once.
+func (what *KeySheet) validate() error {
+	if (what.Month == 0) != (what.Year == 0) {
+		return fmt.Errorf("month and year are given together")
+	}
+
+	last := 31
+	if what.Month != 0 {
+		last = time.Date(what.Year, what.Month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	}
+
+	seen := make(map[int]bool)
+	for _, setting := range what.Days {
+		if setting.Day < 1 || setting.Day > last {
+			return fmt.Errorf("invalid day %d, expected 1-%d", setting.Day, last)
+		}
+
+		if seen[setting.Day] {
+			return fmt.Errorf("duplicate day %d", setting.Day)
+		}
+
+		seen[setting.Day] = true
+	}
+
+	return nil
+}
+
+// LoadFile adds the key sheet of the file.
+func (what *KeySheets) LoadFile(path string) error {
+	sheet, loadError := LoadKeySheet(path)
+	if loadError != nil {
+		return loadError
+	}
+
+	*what = append(*what, sheet)
+
+	return nil
+}
+
+// Network returns the sheets of the network, matched by network name or sheet name in either case.
+func (what KeySheets) Network(name string) KeySheets {
+	var sheets KeySheets
+	for _, sheet := range what {
+		if strings.EqualFold(sheet.Network, name) || strings.EqualFold(sheet.Name(), name) {
+			sheets = append(sheets, sheet)
+		}
+	}
+
+	return sheets
+}
+
+// ForDate returns a copy of the setting of the date and the sheet it is from. Several networks can have a sheet for
+// the same month, then the caller picks one with Network first.
+func (what KeySheets) ForDate(date time.Time) (*Setting, *KeySheet, error) {
+	var found *KeySheet
+	for _, sheet := range what {
+		if !sheet.Covers(date) {
+			continue
+		}
+
+		if found != nil {
+			return nil, nil, fmt.Errorf("key sheets %q and %q are both valid on %v, select a network", found.Name(),
+				sheet.Name(), date.Format(time.DateOnly))
+		}
+
+		found = sheet
+	}
+
+	if found == nil {
+		return nil, nil, fmt.Errorf("no key sheet for %v", date.Format(time.DateOnly))
+	}
+
+	setting, dayError := found.ForDate(date)
+	if dayError != nil {
+		return nil, nil, dayError
+	}
+
+	return setting, found, nil
+}
+
+// parseMonth reads a month as number or by its German or English name.
+func parseMonth(value string) (time.Month, error) {
+	number, numberError := strconv.Atoi(value)
+	if numberError == nil {
+		if number < 1 || number > 12 {
+			return 0, fmt.Errorf("invalid month %d, expected 1-12", number)
+		}
+
+		return time.Month(number), nil
+	}
+
+	for index, name := range germanMonths {
+		if strings.EqualFold(name, value) || strings.EqualFold(time.Month(index+1).String(), value) {
+			return time.Month(index + 1), nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid month %q", value)
+}
@@ -0,0 +1,190 @@
+package settings
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"slices"
+	"strings"
+)
+
+const DefaultMachine = "I"
+
+// DefaultReflector is the reflector of the Enigma I from 1 November 1937.
+const DefaultReflector = "B"
+
+var (
+	// EarlyRotors are the rotors of the Enigma I until 15 December 1938.
+	EarlyRotors = []string{"I", "II", "III"}
+
+	// DefaultRotors are the rotors of the Enigma I from 15 December 1938.
+	DefaultRotors = []string{"I", "II", "III", "IV", "V"}
+)
+
+type Machine struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	EntryWheel  string   `yaml:"entry_wheel"` // Eintrittswalze
+	Stepping    Stepping `yaml:"stepping"`    // Fortschaltung
+	Slots       []Slot   `yaml:"slots"`       // Walzenlage, left to right
+	Reflectors  []string `yaml:"reflectors"`  // Reflektoren
+	PlugBoard   bool     `yaml:"plug_board"`  // Steckerbrett
+	Uhr         bool     `yaml:"uhr"`         // Enigma Uhr
+}
+
+type Slot struct {
+	Rotors []string `yaml:"rotors"`
+	Fixed  bool     `yaml:"fixed"` // does not step, left of the stepping slots
+}
+
+type Machines []*Machine
+
+func GetMachine(name string) (*Machine, error) {
+	registry, registryError := DefaultRegistry()
+	if registryError != nil {
+		return nil, registryError
+	}
+
+	return registry.GetMachine(name)
+}
+
+func GetMachines() (Machines, error) {
+	registry, registryError := DefaultRegistry()
+	if registryError != nil {
+		return nil, registryError
+	}
+
+	return registry.GetMachines(), nil
+}
+
+func (what *Machine) Step(setting *Setting) {
+	// fixed slots are left of the stepping ones
+	first := 0
+	for first < len(setting.Rotors) && first < len(what.Slots) && what.Slots[first].Fixed {
+		first++
+	}
+
+	stepping := setting.Rotors[first:]
+
+	switch what.Stepping {
+	case RatchetStepping:
+		stepping.Move()
+
+	case GearStepping:
+		stepping.MoveGear(&setting.Reflector)
+	}
+}
+
+func (what *Machine) Validate(setting *Setting) error {
+	if len(setting.Rotors) != len(what.Slots) {
+		return fmt.Errorf("invalid rotors length %d, machine %q expects %d", len(setting.Rotors), what.Name, len(what.Slots))
+	}
+
+	for index, rotor := range setting.Rotors {
+		slot := what.Slots[index]
+		if !slices.Contains(slot.Rotors, rotor.Name) && !slices.Contains(rotor.Machines, what.Name) {
+			return fmt.Errorf("invalid rotor %q in slot %d, machine %q expects one of %v", rotor.Name, index+1, what.Name, slot.Rotors)
+		}
+
+		if rotor.Greek && !slot.Fixed {
+			return fmt.Errorf("invalid rotor %q in slot %d, greek rotors only fit fixed slots", rotor.Name, index+1)
+		}
+	}
+
+	if !slices.Contains(what.Reflectors, setting.Reflector.Name) && !slices.Contains(setting.Reflector.Machines, what.Name) {
+		return fmt.Errorf("invalid reflector %q, machine %q expects one of %v", setting.Reflector.Name, what.Name, what.Reflectors)
+	}
+
+	if setting.EntryWheel.Name != what.EntryWheel {
+		return fmt.Errorf("invalid entry wheel %q, machine %q expects %q", setting.EntryWheel.Name, what.Name, what.EntryWheel)
+	}
+
+	if !what.PlugBoard && setting.PlugBoard.Plugged() {
+		return fmt.Errorf("invalid plug board, machine %q has no plug board", what.Name)
+	}
+
+	if !what.Uhr && setting.PlugBoard.Uhr != nil {
+		return fmt.Errorf("invalid uhr, machine %q has no uhr", what.Name)
+	}
+
+	return nil
+}
+
+func (what *Machines) load(data any) error {
+	castData, ok := data.([]byte)
+	if !ok {
+		return fmt.Errorf("invalid machines data, expected []byte, got %T", data)
+	}
+
+	var items Machines
+	decoder := yaml.NewDecoder(bytes.NewReader(castData))
+	decoder.KnownFields(true)
+	parseError := decoder.Decode(&items)
+	if parseError != nil {
+		return fmt.Errorf("failed to parse machines: %v", parseError)
+	}
+
+	normalizeError := items.normalize()
+	if normalizeError != nil {
+		return normalizeError
+	}
+
+	*what = items
+
+	return nil
+}
+
+func (what Machines) normalize() error {
+	for _, machine := range what {
+		machine.Name = strings.ToUpper(machine.Name)
+		machine.EntryWheel = strings.ToUpper(machine.EntryWheel)
+		for index := range machine.Reflectors {
+			machine.Reflectors[index] = strings.ToUpper(machine.Reflectors[index])
+		}
+
+		if len(machine.Slots) == 0 {
+			return fmt.Errorf("invalid machine %q, expected rotor slots", machine.Name)
+		}
+
+		for index := range machine.Slots {
+			if index > 0 && machine.Slots[index].Fixed && !machine.Slots[index-1].Fixed {
+				return fmt.Errorf("invalid machine %q, fixed slots must be left of the stepping ones", machine.Name)
+			}
+
+			rotorNames := make([]string, 0, len(machine.Slots[index].Rotors))
+			for _, rotorName := range machine.Slots[index].Rotors {
+				rotorNames = append(rotorNames, strings.ToUpper(rotorName))
+			}
+
+			machine.Slots[index].Rotors = rotorNames
+		}
+
+		stepping, steppingError := ParseStepping(string(machine.Stepping))
+		if steppingError != nil {
+			return fmt.Errorf("invalid machine %q: %v", machine.Name, steppingError)
+		}
+
+		machine.Stepping = stepping
+	}
+
+	return nil
+}
+
+func loadMachineNames(value any) ([]string, error) {
+	castValue, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid machines %T, expected []any", value)
+	}
+
+	var machineNames []string
+	for _, machineName := range castValue {
+		castMachineName, machineNameOk := machineName.(string)
+		if !machineNameOk {
+			return nil, fmt.Errorf("invalid machine %T, expected string", machineName)
+		}
+
+		machineNames = append(machineNames, strings.ToUpper(castMachineName))
+	}
+
+	return machineNames, nil
+}
@@ -0,0 +1,104 @@
+package settings
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"strings"
+)
+
+// Permutation maps each letter index to another one, the wiring of a rotor, reflector, entry wheel or plug board.
+type Permutation [len(defs.UpperCase)]uint8
+
+func IdentityPermutation() Permutation {
+	var permutation Permutation
+	for index := range permutation {
+		permutation[index] = uint8(index)
+	}
+
+	return permutation
+}
+
+// ParsePermutation reads a wiring from A to Z, the letter at index n is the image of the n-th letter.
+func ParsePermutation(mapping string) (Permutation, error) {
+	var permutation Permutation
+	if len(mapping) != len(permutation) {
+		return permutation, fmt.Errorf("invalid mapping %q, expected %d characters", mapping, len(permutation))
+	}
+
+	var mapped [len(defs.UpperCase)]bool
+	for index, value := range strings.ToUpper(mapping) {
+		letter := strings.IndexRune(defs.UpperCase, value)
+		if letter == -1 {
+			return permutation, fmt.Errorf("invalid mapping value %q", value)
+		}
+
+		if mapped[letter] {
+			return permutation, fmt.Errorf("duplicate mapping value %q", value)
+		}
+
+		mapped[letter] = true
+		permutation[index] = uint8(letter)
+	}
+
+	return permutation, nil
+}
+
+func (what *Permutation) Inverse() Permutation {
+	var inverse Permutation
+	for index, value := range what {
+		inverse[value] = uint8(index)
+	}
+
+	return inverse
+}
+
+// Shifted returns the permutation of a wheel turned by offset positions.
+func (what *Permutation) Shifted(offset int) Permutation {
+	limit := len(what)
+	offset = (offset%limit + limit) % limit
+
+	var shifted Permutation
+	for index := range shifted {
+		shifted[index] = uint8((int(what[(index+offset)%limit]) - offset + limit) % limit)
+	}
+
+	return shifted
+}
+
+// IsValid reports whether every letter is the image of exactly one letter.
+func (what *Permutation) IsValid() bool {
+	var mapped [len(defs.UpperCase)]bool
+	for _, value := range what {
+		if int(value) >= len(what) || mapped[value] {
+			return false
+		}
+
+		mapped[value] = true
+	}
+
+	return true
+}
+
+// IsInvolution reports whether the permutation swaps letters in pairs, like a plug board or a reflector.
+func (what *Permutation) IsInvolution() bool {
+	if !what.IsValid() {
+		return false
+	}
+
+	for index, value := range what {
+		if int(what[value]) != index {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (what *Permutation) String() string {
+	var builder strings.Builder
+	for _, value := range what {
+		builder.WriteByte(defs.UpperCase[value])
+	}
+
+	return builder.String()
+}
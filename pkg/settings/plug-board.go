@@ -7,21 +7,144 @@ import (
 )
 
 type PlugBoard struct {
-	Mapping map[int]int
+	Mapping Permutation
+	Pairs   []string // in plugging order, required by the uhr
+	Uhr     *Uhr
+	wired   bool // the zero value has no cables
+	forward Permutation
+	reverse Permutation
 }
 
 func (what *PlugBoard) Transform(in int) int {
-	out, ok := what.Mapping[in]
-	if !ok {
+	if !what.wired {
 		return in
 	}
 
-	return out
+	return int(what.forward[in])
+}
+
+func (what *PlugBoard) Reverse(in int) int {
+	if !what.wired {
+		return in
+	}
+
+	return int(what.reverse[in])
+}
+
+func (what *PlugBoard) Encrypt(in int) int {
+	return what.Transform(in)
+}
+
+func (what *PlugBoard) Decrypt(in int) int {
+	return what.Reverse(in)
+}
+
+func (what *PlugBoard) Export() ExportPlugBoard {
+	exportedPlugBoard := make(ExportPlugBoard)
+	if !what.wired {
+		return exportedPlugBoard
+	}
+
+	for plug, value := range what.Mapping {
+		if int(value) != plug {
+			exportedPlugBoard[string(defs.UpperCase[plug])] = string(defs.UpperCase[value])
+		}
+	}
+
+	return exportedPlugBoard
+}
+
+// Plugged reports whether any cable is plugged in.
+func (what *PlugBoard) Plugged() bool {
+	return what.wired && what.Mapping != IdentityPermutation()
+}
+
+// Plugs returns the cables as pairs of letters, in plugging order if known, otherwise alphabetically.
+func (what *PlugBoard) Plugs() []string {
+	if len(what.Pairs) > 0 || !what.wired {
+		return what.Pairs
+	}
+
+	var plugs []string
+	for plug, value := range what.Mapping {
+		if int(value) > plug {
+			plugs = append(plugs, string([]byte{defs.UpperCase[plug], defs.UpperCase[value]}))
+		}
+	}
+
+	return plugs
+}
+
+// Connect plugs a cable between two letters, without the uhr.
+func (what *PlugBoard) Connect(one int, two int) error {
+	if !what.wired {
+		what.Reset()
+	}
+
+	if one < 0 || one >= len(what.Mapping) || two < 0 || two >= len(what.Mapping) {
+		return fmt.Errorf("invalid plug board value %d and %d, expected 0-%d", one, two, len(what.Mapping)-1)
+	}
+
+	what.Mapping[one] = uint8(two)
+	what.Mapping[two] = uint8(one)
+
+	return what.wire()
+}
+
+// Reset pulls all cables.
+func (what *PlugBoard) Reset() {
+	what.Mapping = IdentityPermutation()
+	what.Pairs = nil
+	what.wired = true
+	what.forward = what.Mapping
+	what.reverse = what.Mapping
+}
+
+func (what *PlugBoard) SetUhr(position int) error {
+	if what.Uhr == nil {
+		newUhr, uhrError := GetUhr()
+		if uhrError != nil {
+			return uhrError
+		}
+
+		what.Uhr = newUhr
+	}
+
+	positionError := what.Uhr.SetPosition(position)
+	if positionError != nil {
+		return positionError
+	}
+
+	return what.wire()
+}
+
+// wire builds the tables the signal passes, through the uhr if there is one.
+func (what *PlugBoard) wire() error {
+	if !what.wired {
+		what.Reset()
+	}
+
+	if what.Uhr == nil {
+		what.forward = what.Mapping
+		what.reverse = what.Mapping
+		return nil
+	}
+
+	forward, mappingError := what.Uhr.Mapping(what.Pairs)
+	if mappingError != nil {
+		return mappingError
+	}
+
+	what.forward = forward
+	what.reverse = forward.Inverse()
+
+	return nil
 }
 
 func (what *PlugBoard) Parse(in string) error {
-	what.Mapping = make(map[int]int)
+	what.Reset()
 
+	var plugged [len(defs.UpperCase)]bool
 	for _, plug := range strings.Fields(strings.ToUpper(in)) {
 		if len(plug) != 2 {
 			return fmt.Errorf("invalid plug board value %q, expected 2 characters", plug)
@@ -33,33 +156,16 @@ func (what *PlugBoard) Parse(in string) error {
 			return fmt.Errorf("invalid plug board value %q", plug)
 		}
 
-		_, plugOneExists := what.Mapping[plugOne]
-		if plugOneExists {
-			return fmt.Errorf("duplicate plug board value %q", plug)
-		}
-
-		_, plugTwoExists := what.Mapping[plugTwo]
-		if plugTwoExists {
+		if plugged[plugOne] || plugged[plugTwo] {
 			return fmt.Errorf("duplicate plug board value %q", plug)
 		}
 
-		what.Mapping[plugOne] = plugTwo
-		what.Mapping[plugTwo] = plugOne
+		plugged[plugOne] = true
+		plugged[plugTwo] = true
+		what.Mapping[plugOne] = uint8(plugTwo)
+		what.Mapping[plugTwo] = uint8(plugOne)
+		what.Pairs = append(what.Pairs, plug)
 	}
 
-	for _, letter := range defs.UpperCase {
-		plug := strings.IndexRune(defs.UpperCase, letter)
-
-		_, forwardExists := what.Mapping[strings.IndexRune(defs.UpperCase, letter)]
-		if !forwardExists {
-			what.Mapping[plug] = plug
-		}
-
-		_, reverseExists := what.Mapping[strings.IndexRune(defs.UpperCase, letter)]
-		if !reverseExists {
-			what.Mapping[plug] = plug
-		}
-	}
-
-	return nil
+	return what.wire()
 }
@@ -3,59 +3,277 @@ package settings
 import (
 	"fmt"
 	"github.com/r3db34n1an/enigma/pkg/defs"
-	"github.com/r3db34n1an/enigma/pkg/embed"
 	"gopkg.in/yaml.v3"
 	"strings"
 )
 
-var reflectors Reflectors
+const (
+	// UKW-D sockets are labelled without J and Y in German notation, these are the equivalent Bletchley Park letters
+	ukwdGermanLetters    = "ABCDEFGHIKLMNOPQRSTUVWXZ"
+	ukwdBletchleyLetters = "AZYXWVUTSRQPNMLKJIHGFEDC"
+)
 
 type Reflector struct {
-	Name    string
-	Mapping map[int]int
+	Name        string
+	Aliases     []string
+	Machines    []string // machines fitting the reflector besides their own reflector lists
+	Thin        bool     // dünn
+	Rewirable   bool     // umsteckbar
+	Fixed       string   // hard wired pair of a rewirable reflector
+	Wiring      string   // pairs of a rewirable reflector in Bletchley Park notation
+	Settable    bool     // einstellbar
+	Moving      bool     // driven by the rotors
+	Position    int      // Grundstellung
+	RingSetting int      // Ringstellung
+	Mapping     Permutation
 }
 
+type ReflectorNotation string
+
+const (
+	BletchleyNotation ReflectorNotation = "bletchley"
+	GermanNotation    ReflectorNotation = "german"
+)
+
 type Reflectors map[string]*Reflector
 
 func GetReflector(name string) (*Reflector, error) {
-	if reflectors == nil {
-		reflectors = make(Reflectors)
-		loadError := reflectors.load(embed.ReflectorsYaml)
-		if loadError != nil {
-			return nil, fmt.Errorf("failed to load reflectors: %v", loadError)
+	registry, registryError := DefaultRegistry()
+	if registryError != nil {
+		return nil, registryError
+	}
+
+	return registry.GetReflector(name)
+}
+
+func (what *Reflector) Reflect(in int) int {
+	limit := len(defs.UpperCase)
+
+	offset := (what.Position - what.RingSetting + limit) % limit
+	out := int(what.Mapping[(in+offset)%limit])
+
+	return (out + limit - offset) % limit
+}
+
+func (what *Reflector) move() {
+	limit := len(defs.UpperCase)
+	what.Position = (what.Position + 1) % limit
+}
+
+func (what *Reflector) Rewire(wiring string, notation ReflectorNotation) error {
+	if !what.Rewirable {
+		return fmt.Errorf("reflector %q is not rewirable", what.Name)
+	}
+
+	notation = ReflectorNotation(strings.ToLower(string(notation)))
+	if notation != "" && notation != BletchleyNotation && notation != GermanNotation {
+		return fmt.Errorf("invalid reflector notation %q", notation)
+	}
+
+	var pairs []string
+	for _, pair := range strings.Fields(strings.ToUpper(wiring)) {
+		if len(pair) != 2 {
+			return fmt.Errorf("invalid reflector pair %q, expected 2 characters", pair)
+		}
+
+		if notation == GermanNotation {
+			converted := ""
+			for _, letter := range pair {
+				index := strings.IndexRune(ukwdGermanLetters, letter)
+				if index < 0 {
+					return fmt.Errorf("invalid reflector pair %q in german notation", pair)
+				}
+
+				converted += string(ukwdBletchleyLetters[index])
+			}
+
+			pair = converted
 		}
+
+		pairs = append(pairs, pair)
 	}
 
-	reflector, ok := reflectors[strings.ToUpper(name)]
-	if !ok {
-		return nil, fmt.Errorf("reflector %q not found", name)
+	expectedPairs := (len(defs.UpperCase) - len(what.Fixed)) / 2
+	if len(pairs) != expectedPairs {
+		return fmt.Errorf("invalid reflector wiring, expected %d pairs, got %d", expectedPairs, len(pairs))
 	}
 
-	return reflector, nil
+	var mapping Permutation
+	var mapped [len(defs.UpperCase)]bool
+	for _, pair := range append([]string{what.Fixed}, pairs...) {
+		one := strings.IndexRune(defs.UpperCase, rune(pair[0]))
+		two := strings.IndexRune(defs.UpperCase, rune(pair[1]))
+		if one == -1 || two == -1 {
+			return fmt.Errorf("invalid reflector pair %q", pair)
+		}
+
+		if one == two || mapped[one] || mapped[two] {
+			return fmt.Errorf("duplicate reflector pair value %q", pair)
+		}
+
+		mapped[one] = true
+		mapped[two] = true
+		mapping[one] = uint8(two)
+		mapping[two] = uint8(one)
+	}
+
+	what.Mapping = mapping
+	what.Wiring = strings.Join(pairs, " ")
+
+	return what.validate()
 }
 
-func (what *Reflector) Reflect(in int) int {
-	out, ok := what.Mapping[in]
-	if !ok {
-		return -1
+func (what *Reflector) validate() error {
+	if what.Rewirable && what.Wiring == "" {
+		return fmt.Errorf("reflector %q requires a wiring", what.Name)
+	}
+
+	if what.Position < 0 || what.Position > 25 || what.RingSetting < 0 || what.RingSetting > 25 {
+		return fmt.Errorf("invalid reflector position %d and ring %d, expected 0-25", what.Position, what.RingSetting)
+	}
+
+	if !what.Settable && (what.Position != 0 || what.RingSetting != 0) {
+		return fmt.Errorf("reflector %q is not settable", what.Name)
+	}
+
+	if !what.Mapping.IsValid() {
+		return fmt.Errorf("invalid reflector mapping %q, expected a permutation", what.Mapping.String())
 	}
 
-	return out
+	for index, value := range what.Mapping {
+		if int(value) == index {
+			return fmt.Errorf("invalid reflector mapping, %q maps to itself", defs.UpperCase[index])
+		}
+
+		if int(what.Mapping[value]) != index {
+			return fmt.Errorf("invalid reflector mapping, %q and %q are not paired", defs.UpperCase[index], defs.UpperCase[value])
+		}
+	}
+
+	return nil
+}
+
+// validateRewirable checks a rewirable reflector before the setting wires it: no mapping and a hard wired pair of two
+// different letters.
+func (what *Reflector) validateRewirable() error {
+	if what.Mapping != (Permutation{}) {
+		return fmt.Errorf("invalid rewirable reflector, expected no mapping")
+	}
+
+	if len(what.Fixed) != 2 || what.Fixed[0] == what.Fixed[1] || !strings.ContainsRune(defs.UpperCase, rune(what.Fixed[0])) ||
+		!strings.ContainsRune(defs.UpperCase, rune(what.Fixed[1])) {
+		return fmt.Errorf("invalid reflector fixed pair %q, expected 2 different letters", what.Fixed)
+	}
+
+	return nil
 }
 
 func (what *Reflector) load(data any) error {
-	reflectors = make(Reflectors)
 	switch castData := data.(type) {
 	case string:
-		what.Mapping = make(map[int]int)
-		for k, v := range strings.ToUpper(castData) {
-			what.Mapping[k] = strings.IndexRune(defs.UpperCase, v)
-			if what.Mapping[k] == -1 {
-				return fmt.Errorf("invalid reflector value %q", v)
+		return what.loadMapping(castData)
+
+	case map[string]any:
+		for attributeName, attributeValue := range castData {
+			switch strings.ToLower(attributeName) {
+			case "mapping":
+				castValue, ok := attributeValue.(string)
+				if !ok {
+					return fmt.Errorf("invalid reflector mapping %T, expected string", attributeValue)
+				}
+
+				mappingError := what.loadMapping(castValue)
+				if mappingError != nil {
+					return mappingError
+				}
+
+			case "thin":
+				castValue, ok := attributeValue.(bool)
+				if !ok {
+					return fmt.Errorf("invalid reflector thin %T, expected bool", attributeValue)
+				}
+
+				what.Thin = castValue
+
+			case "settable":
+				castValue, ok := attributeValue.(bool)
+				if !ok {
+					return fmt.Errorf("invalid reflector settable %T, expected bool", attributeValue)
+				}
+
+				what.Settable = castValue
+
+			case "moving":
+				castValue, ok := attributeValue.(bool)
+				if !ok {
+					return fmt.Errorf("invalid reflector moving %T, expected bool", attributeValue)
+				}
+
+				what.Moving = castValue
+
+			case "rewirable":
+				castValue, ok := attributeValue.(bool)
+				if !ok {
+					return fmt.Errorf("invalid reflector rewirable %T, expected bool", attributeValue)
+				}
+
+				what.Rewirable = castValue
+
+			case "fixed":
+				castValue, ok := attributeValue.(string)
+				if !ok || len(castValue) != 2 {
+					return fmt.Errorf("invalid reflector fixed pair %v, expected 2 characters", attributeValue)
+				}
+
+				what.Fixed = strings.ToUpper(castValue)
+
+			case "machines":
+				machineNames, machinesError := loadMachineNames(attributeValue)
+				if machinesError != nil {
+					return fmt.Errorf("invalid reflector machines: %v", machinesError)
+				}
+
+				what.Machines = machineNames
+
+			case "aliases":
+				castValue, ok := attributeValue.([]any)
+				if !ok {
+					return fmt.Errorf("invalid reflector aliases %T, expected []any", attributeValue)
+				}
+
+				for _, alias := range castValue {
+					castAlias, aliasOk := alias.(string)
+					if !aliasOk {
+						return fmt.Errorf("invalid reflector alias %T, expected string", alias)
+					}
+
+					what.Aliases = append(what.Aliases, strings.ToUpper(castAlias))
+				}
+
+			default:
+				return fmt.Errorf("invalid reflector attribute %q", attributeName)
 			}
 		}
+
+	default:
+		return fmt.Errorf("invalid reflector %T, expected string or map[string]any", data)
 	}
 
+	if what.Rewirable {
+		return what.validateRewirable()
+	}
+
+	return what.validate()
+}
+
+func (what *Reflector) loadMapping(value string) error {
+	mapping, mappingError := ParsePermutation(value)
+	if mappingError != nil {
+		return fmt.Errorf("invalid reflector mapping: %v", mappingError)
+	}
+
+	what.Mapping = mapping
+
 	return nil
 }
 
@@ -71,6 +289,10 @@ func (what *Reflectors) load(data any) error {
 		return fmt.Errorf("failed to parse reflectors: %v", parseError)
 	}
 
+	return what.loadItems(items)
+}
+
+func (what *Reflectors) loadItems(items map[string]any) error {
 	newReflectors := make(map[string]*Reflector)
 	for reflectorName, reflectorValue := range items {
 		reflector := &Reflector{
@@ -79,10 +301,13 @@ func (what *Reflectors) load(data any) error {
 
 		loadError := reflector.load(reflectorValue)
 		if loadError != nil {
-			return fmt.Errorf("failed to load rotor %q: %v", reflectorName, loadError)
+			return fmt.Errorf("failed to load reflector %q: %v", reflectorName, loadError)
 		}
 
 		newReflectors[reflector.Name] = reflector
+		for _, alias := range reflector.Aliases {
+			newReflectors[alias] = reflector
+		}
 	}
 
 	*what = newReflectors
@@ -0,0 +1,517 @@
+package settings
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/embed"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const NamespaceSeparator = ":"
+
+var (
+	defaultRegistry      *Registry
+	defaultRegistryOnce  sync.Once
+	defaultRegistryError error
+)
+
+// Registry holds rotors, reflectors, entry wheels and machines. Lookups fall back to the parent registry, so a
+// registry without namespace shadows the built-ins, while one with namespace adds its components as NAMESPACE:NAME.
+type Registry struct {
+	Namespace   string
+	mutex       sync.RWMutex
+	parent      *Registry
+	rotors      Rotors
+	reflectors  Reflectors
+	entryWheels EntryWheels
+	machines    Machines
+}
+
+type RotorDefinition struct {
+	Name     string
+	Mapping  string   // wiring from A to Z
+	Notches  string   // turnover letters
+	Greek    bool     // Zusatzwalze
+	Machines []string // machines the rotor fits into
+}
+
+type ReflectorDefinition struct {
+	Name      string
+	Mapping   string // wiring from A to Z
+	Thin      bool   // dünn
+	Settable  bool   // einstellbar
+	Moving    bool   // driven by the rotors
+	Rewirable bool   // umsteckbar, wired by the setting instead of Mapping
+	Fixed     string // hard wired pair of a rewirable reflector
+	Machines  []string
+}
+
+func DefaultRegistry() (*Registry, error) {
+	defaultRegistryOnce.Do(func() {
+		registry := newRegistry("", nil)
+
+		loadError := registry.loadEmbedded()
+		if loadError != nil {
+			defaultRegistryError = fmt.Errorf("failed to load built-in components: %v", loadError)
+			return
+		}
+
+		defaultRegistry = registry
+	})
+
+	return defaultRegistry, defaultRegistryError
+}
+
+func NewRegistry(namespace string) (*Registry, error) {
+	if strings.Contains(namespace, NamespaceSeparator) {
+		return nil, fmt.Errorf("invalid namespace %q, must not contain %q", namespace, NamespaceSeparator)
+	}
+
+	parent, parentError := DefaultRegistry()
+	if parentError != nil {
+		return nil, parentError
+	}
+
+	return newRegistry(namespace, parent), nil
+}
+
+func newRegistry(namespace string, parent *Registry) *Registry {
+	return &Registry{
+		Namespace:   strings.ToUpper(namespace),
+		parent:      parent,
+		rotors:      make(Rotors),
+		reflectors:  make(Reflectors),
+		entryWheels: make(EntryWheels),
+	}
+}
+
+func (what *Registry) GetRotor(name string) (*Rotor, error) {
+	what.mutex.RLock()
+	rotor, ok := what.rotors[strings.ToUpper(name)]
+	what.mutex.RUnlock()
+
+	if !ok {
+		if what.parent != nil {
+			return what.parent.GetRotor(name)
+		}
+
+		return nil, fmt.Errorf("rotor %q not found", name)
+	}
+
+	newRotor := rotor
+	return &newRotor, nil
+}
+
+func (what *Registry) GetReflector(name string) (*Reflector, error) {
+	what.mutex.RLock()
+	reflector, ok := what.reflectors[strings.ToUpper(name)]
+	what.mutex.RUnlock()
+
+	if !ok {
+		if what.parent != nil {
+			return what.parent.GetReflector(name)
+		}
+
+		return nil, fmt.Errorf("reflector %q not found", name)
+	}
+
+	return reflector, nil
+}
+
+func (what *Registry) GetEntryWheel(name string) (*EntryWheel, error) {
+	what.mutex.RLock()
+	entryWheel, ok := what.entryWheels[strings.ToUpper(name)]
+	what.mutex.RUnlock()
+
+	if !ok {
+		if what.parent != nil {
+			return what.parent.GetEntryWheel(name)
+		}
+
+		return nil, fmt.Errorf("entry wheel %q not found", name)
+	}
+
+	return entryWheel, nil
+}
+
+func (what *Registry) GetMachine(name string) (*Machine, error) {
+	for _, machine := range what.GetMachines() {
+		if machine.Name == strings.ToUpper(name) {
+			return machine, nil
+		}
+	}
+
+	return nil, fmt.Errorf("machine %q not found", name)
+}
+
+// GetMachines returns the machines of the registry before the ones of its parent.
+func (what *Registry) GetMachines() Machines {
+	what.mutex.RLock()
+	allMachines := append(Machines{}, what.machines...)
+	what.mutex.RUnlock()
+
+	if what.parent != nil {
+		allMachines = append(allMachines, what.parent.GetMachines()...)
+	}
+
+	return allMachines
+}
+
+func (what *Registry) AddRotor(definition RotorDefinition) error {
+	rotor := Rotor{
+		Name:  strings.ToUpper(definition.Name),
+		Greek: definition.Greek,
+	}
+
+	mappingError := rotor.loadMapping(definition.Mapping)
+	if mappingError != nil {
+		return fmt.Errorf("invalid rotor %q: %v", definition.Name, mappingError)
+	}
+
+	var notches []string
+	for _, notch := range strings.ToUpper(definition.Notches) {
+		notches = append(notches, string(notch))
+	}
+
+	notchesError := rotor.loadNotches(notches)
+	if notchesError != nil {
+		return fmt.Errorf("invalid rotor %q: %v", definition.Name, notchesError)
+	}
+
+	for _, machineName := range definition.Machines {
+		rotor.Machines = append(rotor.Machines, strings.ToUpper(machineName))
+	}
+
+	what.mutex.Lock()
+	defer what.mutex.Unlock()
+
+	return what.addRotor(rotor)
+}
+
+func (what *Registry) AddReflector(definition ReflectorDefinition) error {
+	reflector := &Reflector{
+		Name:      strings.ToUpper(definition.Name),
+		Thin:      definition.Thin,
+		Settable:  definition.Settable,
+		Moving:    definition.Moving,
+		Rewirable: definition.Rewirable,
+		Fixed:     strings.ToUpper(definition.Fixed),
+	}
+
+	if !definition.Rewirable || len(definition.Mapping) > 0 {
+		mappingError := reflector.loadMapping(definition.Mapping)
+		if mappingError != nil {
+			return fmt.Errorf("invalid reflector %q: %v", definition.Name, mappingError)
+		}
+	}
+
+	for _, machineName := range definition.Machines {
+		reflector.Machines = append(reflector.Machines, strings.ToUpper(machineName))
+	}
+
+	what.mutex.Lock()
+	defer what.mutex.Unlock()
+
+	return what.addReflector(reflector)
+}
+
+func (what *Registry) AddMachine(machine Machine) error {
+	newMachine := machine
+	normalizeError := Machines{&newMachine}.normalize()
+	if normalizeError != nil {
+		return normalizeError
+	}
+
+	what.mutex.Lock()
+	defer what.mutex.Unlock()
+
+	return what.addMachine(&newMachine)
+}
+
+func (what *Registry) LoadFile(path string) error {
+	data, readError := os.ReadFile(path) // #nosec:G304
+	if readError != nil {
+		return fmt.Errorf("failed to read %q: %v", path, readError)
+	}
+
+	return what.Load(data)
+}
+
+func (what *Registry) LoadReader(reader io.Reader) error {
+	data, readError := io.ReadAll(reader)
+	if readError != nil {
+		return fmt.Errorf("failed to read registry: %v", readError)
+	}
+
+	return what.Load(data)
+}
+
+// Load reads rotors, reflectors, entry wheels and machines in the format of the embedded configuration files, grouped
+// under the keys rotors, reflectors, entry_wheels and machines.
+func (what *Registry) Load(data []byte) error {
+	var items struct {
+		Rotors      map[string]any `yaml:"rotors"`
+		Reflectors  map[string]any `yaml:"reflectors"`
+		EntryWheels map[string]any `yaml:"entry_wheels"`
+		Machines    Machines       `yaml:"machines"`
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	parseError := decoder.Decode(&items)
+	if parseError != nil {
+		return fmt.Errorf("failed to parse registry: %v", parseError)
+	}
+
+	var newRotors Rotors
+	loadError := newRotors.loadItems(items.Rotors)
+	if loadError != nil {
+		return loadError
+	}
+
+	var newReflectors Reflectors
+	loadError = newReflectors.loadItems(items.Reflectors)
+	if loadError != nil {
+		return loadError
+	}
+
+	var newEntryWheels EntryWheels
+	loadError = newEntryWheels.loadItems(items.EntryWheels)
+	if loadError != nil {
+		return loadError
+	}
+
+	loadError = items.Machines.normalize()
+	if loadError != nil {
+		return loadError
+	}
+
+	return what.add(newRotors, newReflectors, newEntryWheels, items.Machines)
+}
+
+func (what *Registry) loadEmbedded() error {
+	var newRotors Rotors
+	loadError := newRotors.load(embed.RotorsYaml)
+	if loadError != nil {
+		return loadError
+	}
+
+	var newReflectors Reflectors
+	loadError = newReflectors.load(embed.ReflectorsYaml)
+	if loadError != nil {
+		return loadError
+	}
+
+	var newEntryWheels EntryWheels
+	loadError = newEntryWheels.load(embed.EntryWheelsYaml)
+	if loadError != nil {
+		return loadError
+	}
+
+	var newMachines Machines
+	loadError = newMachines.load(embed.MachinesYaml)
+	if loadError != nil {
+		return loadError
+	}
+
+	return what.add(newRotors, newReflectors, newEntryWheels, newMachines)
+}
+
+func (what *Registry) add(newRotors Rotors, newReflectors Reflectors, newEntryWheels EntryWheels, newMachines Machines) error {
+	what.mutex.Lock()
+	defer what.mutex.Unlock()
+
+	for _, rotor := range newRotors {
+		addError := what.addRotor(rotor)
+		if addError != nil {
+			return addError
+		}
+	}
+
+	for name, reflector := range newReflectors {
+		// aliases point to the same reflector
+		if name != reflector.Name {
+			continue
+		}
+
+		addError := what.addReflector(reflector)
+		if addError != nil {
+			return addError
+		}
+	}
+
+	for _, entryWheel := range newEntryWheels {
+		entryWheel.Name = what.qualify(entryWheel.Name)
+		what.entryWheels[entryWheel.Name] = entryWheel
+	}
+
+	for _, machine := range newMachines {
+		addError := what.addMachine(machine)
+		if addError != nil {
+			return addError
+		}
+	}
+
+	return nil
+}
+
+func (what *Registry) addRotor(rotor Rotor) error {
+	validateError := rotor.validate()
+	if validateError != nil {
+		return fmt.Errorf("invalid rotor %q: %v", rotor.Name, validateError)
+	}
+
+	bareName := strings.ToUpper(rotor.Name)
+	rotor.Name = what.qualify(rotor.Name)
+	rotor.Machines = what.localMachines(rotor.Machines)
+	what.rotors[rotor.Name] = rotor
+
+	// machines added before the rotor name it without namespace
+	if bareName != rotor.Name {
+		for _, machine := range what.machines {
+			for index := range machine.Slots {
+				machine.Slots[index].Rotors = qualifyName(machine.Slots[index].Rotors, bareName, rotor.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (what *Registry) addReflector(reflector *Reflector) error {
+	// rewirable reflectors get their wiring from the setting
+	validate := reflector.validate
+	if reflector.Rewirable {
+		validate = reflector.validateRewirable
+	}
+
+	validateError := validate()
+	if validateError != nil {
+		return fmt.Errorf("invalid reflector %q: %v", reflector.Name, validateError)
+	}
+
+	bareName := strings.ToUpper(reflector.Name)
+	reflector.Name = what.qualify(reflector.Name)
+	reflector.Machines = what.localMachines(reflector.Machines)
+	what.reflectors[reflector.Name] = reflector
+	if bareName != reflector.Name {
+		for _, machine := range what.machines {
+			machine.Reflectors = qualifyName(machine.Reflectors, bareName, reflector.Name)
+		}
+	}
+
+	for index, alias := range reflector.Aliases {
+		reflector.Aliases[index] = what.qualify(alias)
+		what.reflectors[reflector.Aliases[index]] = reflector
+	}
+
+	return nil
+}
+
+func (what *Registry) addMachine(machine *Machine) error {
+	bareName := strings.ToUpper(machine.Name)
+	machine.Name = what.qualify(machine.Name)
+
+	// the machine names the components of its own registry without namespace
+	slots := make([]Slot, len(machine.Slots))
+	for index, slot := range machine.Slots {
+		slots[index] = Slot{Rotors: make([]string, len(slot.Rotors)), Fixed: slot.Fixed}
+		for position, rotorName := range slot.Rotors {
+			slots[index].Rotors[position] = what.local(rotorName, func(name string) bool {
+				_, found := what.rotors[name]
+				return found
+			})
+		}
+	}
+
+	machine.Slots = slots
+	reflectorNames := make([]string, len(machine.Reflectors))
+	for index, reflectorName := range machine.Reflectors {
+		reflectorNames[index] = what.local(reflectorName, func(name string) bool {
+			_, found := what.reflectors[name]
+			return found
+		})
+	}
+
+	machine.Reflectors = reflectorNames
+	if machine.EntryWheel != "" {
+		machine.EntryWheel = what.local(machine.EntryWheel, func(name string) bool {
+			_, found := what.entryWheels[name]
+			return found
+		})
+	}
+
+	// components added before the machine name it without namespace
+	if bareName != machine.Name {
+		for name, rotor := range what.rotors {
+			rotor.Machines = qualifyName(rotor.Machines, bareName, machine.Name)
+			what.rotors[name] = rotor
+		}
+
+		for _, reflector := range what.reflectors {
+			reflector.Machines = qualifyName(reflector.Machines, bareName, machine.Name)
+		}
+	}
+
+	for index, existing := range what.machines {
+		if existing.Name == machine.Name {
+			what.machines[index] = machine
+			return nil
+		}
+	}
+
+	what.machines = append(what.machines, machine)
+
+	return nil
+}
+
+// local qualifies the name when the registry itself holds a component of that name, other names stay with the parent.
+func (what *Registry) local(name string, holds func(string) bool) string {
+	qualified := what.qualify(name)
+	if holds(qualified) {
+		return qualified
+	}
+
+	return strings.ToUpper(name)
+}
+
+// localMachines qualifies the names of the machines the registry itself holds.
+func (what *Registry) localMachines(machineNames []string) []string {
+	var localNames []string
+	for _, machineName := range machineNames {
+		localNames = append(localNames, what.local(machineName, func(name string) bool {
+			return slices.ContainsFunc(what.machines, func(machine *Machine) bool {
+				return machine.Name == name
+			})
+		}))
+	}
+
+	return localNames
+}
+
+// qualifyName replaces the bare name in the names, on a copy.
+func qualifyName(names []string, bareName string, qualifiedName string) []string {
+	index := slices.Index(names, bareName)
+	if index < 0 {
+		return names
+	}
+
+	names = slices.Clone(names)
+	names[index] = qualifiedName
+
+	return names
+}
+
+func (what *Registry) qualify(name string) string {
+	name = strings.ToUpper(name)
+	if what.Namespace == "" || strings.HasPrefix(name, what.Namespace+NamespaceSeparator) {
+		return name
+	}
+
+	return what.Namespace + NamespaceSeparator + name
+}
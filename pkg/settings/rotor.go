@@ -3,41 +3,36 @@ package settings
 import (
 	"fmt"
 	"github.com/r3db34n1an/enigma/pkg/defs"
-	"github.com/r3db34n1an/enigma/pkg/embed"
 	"gopkg.in/yaml.v3"
+	"slices"
 	"strings"
+	"unicode"
 )
 
-var rotors Rotors
-
 type Rotor struct {
 	Name        string
-	Notches     []int // Kerben
-	Position    int   // Grundstellung
-	RingSetting int   // Ringstellung
-	Forward     map[int]int
-	Reverse     map[int]int
+	Machines    []string // machines fitting the rotor besides their own rotor lists
+	Notches     []int    // Kerben
+	Greek       bool     // Zusatzwalze
+	Position    int      // Grundstellung
+	RingSetting int      // Ringstellung
+	Forward     Permutation
+	Reverse     Permutation
 }
 
 type Rotors map[string]Rotor
 type RotorGroup []*Rotor
 
-func GetRotor(name string) (*Rotor, error) {
-	if rotors == nil {
-		rotors = make(Rotors)
-		loadError := rotors.load(embed.RotorsYaml)
-		if loadError != nil {
-			return nil, fmt.Errorf("failed to load rotors: %v", loadError)
-		}
-	}
+// RotorPositions is the number of window positions of three rotors.
+const RotorPositions = 26 * 26 * 26
 
-	rotor, ok := rotors[strings.ToUpper(name)]
-	if !ok {
-		return nil, fmt.Errorf("rotor %q not found", name)
+func GetRotor(name string) (*Rotor, error) {
+	registry, registryError := DefaultRegistry()
+	if registryError != nil {
+		return nil, registryError
 	}
 
-	newRotor := rotor
-	return &newRotor, nil
+	return registry.GetRotor(name)
 }
 
 func (what *Rotor) ParseRingSetting(name string) error {
@@ -68,27 +63,22 @@ func (what *Rotor) ParsePosition(name string) error {
 	return nil
 }
 
-func (what *Rotor) encrypt(in int) int {
+func (what *Rotor) Encrypt(in int) int {
 	limit := len(defs.UpperCase)
 
-	advance := what.Position - what.RingSetting
-	in += limit + advance
-	in %= limit
-	in = what.Forward[in]
-	in += limit - advance
-	in %= limit
+	offset := (what.Position - what.RingSetting + limit) % limit
+	out := int(what.Forward[(in+offset)%limit])
 
-	return in
+	return (out + limit - offset) % limit
 }
 
-func (what *Rotor) decrypt(in int) int {
+func (what *Rotor) Decrypt(in int) int {
 	limit := len(defs.UpperCase)
 
-	in = (in - what.RingSetting + what.Position + limit) % limit
-	in = what.Reverse[in]
-	in = (in + what.RingSetting - what.Position + limit) % limit
+	offset := (what.Position - what.RingSetting + limit) % limit
+	out := int(what.Reverse[(in+offset)%limit])
 
-	return in
+	return (out + limit - offset) % limit
 }
 
 func (what *Rotor) move() {
@@ -114,71 +104,130 @@ func (what *Rotor) load(rotorValue any) error {
 			case "mapping":
 				switch castRotorAttributeValue := rotorAttributeValue.(type) {
 				case string:
-					if len(castRotorAttributeValue) != 26 {
-						return fmt.Errorf("invalid rotor mapping %q, expected 26 characters", rotorAttributeValue)
+					mappingError := what.loadMapping(castRotorAttributeValue)
+					if mappingError != nil {
+						return mappingError
 					}
 
-					what.Forward = make(map[int]int)
-					what.Reverse = make(map[int]int)
-					for index, value := range strings.ToUpper(castRotorAttributeValue) {
-						mapped := strings.IndexRune(defs.UpperCase, value)
-						if mapped == -1 {
-							return fmt.Errorf("invalid rotor mapping value %q", value)
-						}
-
-						_, forwardExists := what.Forward[index]
-						if forwardExists {
-							return fmt.Errorf("duplicate forward rotor mapping value %q", value)
-						}
-
-						_, reverseExists := what.Reverse[mapped]
-						if reverseExists {
-							return fmt.Errorf("duplicate reverse rotor mapping value %q", value)
-						}
-
-						what.Forward[index] = mapped
-						what.Reverse[mapped] = index
-					}
+				default:
+					return fmt.Errorf("invalid rotor mapping %T, expected string", rotorAttributeValue)
 				}
 
 			case "notches":
 				switch castRotorAttributeValue := rotorAttributeValue.(type) {
 				case []any:
-					what.Notches = make([]int, 0)
+					var notches []string
 					for _, notch := range castRotorAttributeValue {
 						switch castNotch := notch.(type) {
 						case string:
-							if len(castNotch) != 1 {
-								return fmt.Errorf("invalid rotor notch %q, expected 1 character", notch)
-							}
-
-							what.Notches = append(what.Notches, strings.IndexRune(defs.UpperCase, rune(castNotch[0])))
+							notches = append(notches, castNotch)
 
 						default:
-							return fmt.Errorf("invalid rotor notch %T, expected int or string", notch)
+							return fmt.Errorf("invalid rotor notch %T, expected string", notch)
 						}
 					}
+
+					notchesError := what.loadNotches(notches)
+					if notchesError != nil {
+						return notchesError
+					}
+
+				default:
+					return fmt.Errorf("invalid rotor notches %T, expected []any", rotorAttributeValue)
 				}
 
+			case "greek":
+				switch castRotorAttributeValue := rotorAttributeValue.(type) {
+				case bool:
+					what.Greek = castRotorAttributeValue
+
+				default:
+					return fmt.Errorf("invalid rotor greek %T, expected bool", rotorAttributeValue)
+				}
+
+			case "machines":
+				machineNames, machinesError := loadMachineNames(rotorAttributeValue)
+				if machinesError != nil {
+					return fmt.Errorf("invalid rotor machines: %v", machinesError)
+				}
+
+				what.Machines = machineNames
+
 			default:
 				return fmt.Errorf("invalid rotor attiribute %q", rotorAttributeName)
 			}
 		}
 
 	default:
-		return fmt.Errorf("invalid reflector %T, expected map[string]any", rotorValue)
+		return fmt.Errorf("invalid rotor %T, expected map[string]any", rotorValue)
+	}
+
+	return what.validate()
+}
+
+func (what *Rotor) loadMapping(mapping string) error {
+	forward, mappingError := ParsePermutation(mapping)
+	if mappingError != nil {
+		return fmt.Errorf("invalid rotor mapping: %v", mappingError)
+	}
+
+	what.Forward = forward
+	what.Reverse = forward.Inverse()
+
+	return nil
+}
+
+func (what *Rotor) loadNotches(notches []string) error {
+	what.Notches = make([]int, 0)
+	for _, notch := range notches {
+		if len(notch) != 1 {
+			return fmt.Errorf("invalid rotor notch %q, expected 1 character", notch)
+		}
+
+		index := strings.IndexRune(defs.UpperCase, unicode.ToUpper(rune(notch[0])))
+		if index == -1 {
+			return fmt.Errorf("invalid rotor notch %q", notch)
+		}
+
+		if slices.Contains(what.Notches, index) {
+			return fmt.Errorf("duplicate rotor notch %q", notch)
+		}
+
+		what.Notches = append(what.Notches, index)
+	}
+
+	return nil
+}
+
+func (what *Rotor) validate() error {
+	if !what.Forward.IsValid() || what.Reverse != what.Forward.Inverse() {
+		return fmt.Errorf("invalid rotor mapping %q, expected a permutation", what.Forward.String())
+	}
+
+	if what.Greek && len(what.Notches) > 0 {
+		return fmt.Errorf("invalid greek rotor, expected no notches")
 	}
 
 	return nil
 }
 
 func (what *Rotors) load(data any) error {
+	castData, ok := data.([]byte)
+	if !ok {
+		return fmt.Errorf("invalid rotors data, expected []byte, got %T", data)
+	}
+
 	var items map[string]any
-	parseError := yaml.Unmarshal(data.([]byte), &items)
+	parseError := yaml.Unmarshal(castData, &items)
 	if parseError != nil {
 		return fmt.Errorf("failed to parse rotors: %v", parseError)
 	}
 
+	return what.loadItems(items)
+}
+
+func (what *Rotors) loadItems(items map[string]any) error {
+	newRotors := make(Rotors)
 	for rotorName, rotorValue := range items {
 		rotor := Rotor{
 			Name: strings.ToUpper(rotorName),
@@ -189,9 +238,11 @@ func (what *Rotors) load(data any) error {
 			return fmt.Errorf("failed to load rotor %q: %v", rotorName, loadError)
 		}
 
-		rotors[rotor.Name] = rotor
+		newRotors[rotor.Name] = rotor
 	}
 
+	*what = newRotors
+
 	return nil
 }
 
@@ -226,13 +277,47 @@ func (what *RotorGroup) Move() {
 	}
 }
 
+// Index returns the positions of the rotors as one number, the leftmost rotor is the highest digit.
+func (what *RotorGroup) Index() int {
+	index := 0
+	for _, rotor := range *what {
+		index = index*len(defs.UpperCase) + rotor.Position
+	}
+
+	return index
+}
+
+// SetIndex sets the positions of the rotors from a number as returned by Index.
+func (what *RotorGroup) SetIndex(index int) {
+	for rotor := len(*what) - 1; rotor >= 0; rotor-- {
+		(*what)[rotor].Position = index % len(defs.UpperCase)
+		index /= len(defs.UpperCase)
+	}
+}
+
+func (what *RotorGroup) MoveGear(reflector *Reflector) {
+	for index := len(*what) - 1; index >= 0; index-- {
+		rotor := (*what)[index]
+		carry := rotor.shouldMove()
+		rotor.move()
+
+		if !carry {
+			return
+		}
+	}
+
+	if reflector != nil && reflector.Moving {
+		reflector.move()
+	}
+}
+
 func (what *RotorGroup) encrypt(in int, rotors RotorGroup) int {
 	if len(rotors) == 0 {
 		return in
 	}
 
 	in = what.encrypt(in, rotors[1:])
-	in = rotors[0].encrypt(in)
+	in = rotors[0].Encrypt(in)
 
 	return in
 }
@@ -242,8 +327,24 @@ func (what *RotorGroup) decrypt(in int, rotors RotorGroup) int {
 		return in
 	}
 
-	in = rotors[0].decrypt(in)
+	in = rotors[0].Decrypt(in)
 	in = what.decrypt(in, rotors[1:])
 
 	return in
 }
+
+// RotorOrders returns every order of three different rotors, the Walzenlagen the rotors allow.
+func RotorOrders(rotors []string) [][]string {
+	var orders [][]string
+	for _, left := range rotors {
+		for _, middle := range rotors {
+			for _, right := range rotors {
+				if left != middle && middle != right && left != right {
+					orders = append(orders, []string{left, middle, right})
+				}
+			}
+		}
+	}
+
+	return orders
+}
@@ -0,0 +1,118 @@
+package settings
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"strings"
+)
+
+// Scrambler holds the permutation of entry wheel, rotors and reflector for every core offset, that is the position
+// minus the ring setting of each rotor and of a settable reflector. Neither the ring settings nor the plug board are
+// part of it, so one scrambler serves all of them for a wheel order. It does not change once built and may be shared
+// between goroutines.
+type Scrambler struct {
+	key    string
+	tables []Permutation
+}
+
+func NewScrambler(setting *Setting) (*Scrambler, error) {
+	if setting == nil {
+		return nil, fmt.Errorf("no setting")
+	}
+
+	limit := len(defs.UpperCase)
+
+	// seen from the right side of a rotor, the reflector and the rotors left of it act like a single reflector
+	reflectors := []Permutation{setting.Reflector.Mapping}
+	if setting.Reflector.Settable {
+		reflectors = make([]Permutation, limit)
+		for offset := range reflectors {
+			reflectors[offset] = setting.Reflector.Mapping.Shifted(offset)
+		}
+	}
+
+	// the leftmost rotor is the highest digit of the index, a settable reflector the lowest
+	reflectorOffsets := len(reflectors)
+	for _, rotor := range setting.Rotors {
+		next := make([]Permutation, len(reflectors)*limit)
+		for offset := range limit {
+			forward := rotor.Forward.Shifted(offset)
+			reverse := rotor.Reverse.Shifted(offset)
+			for index, reflector := range reflectors {
+				nextIndex := (index/reflectorOffsets*limit+offset)*reflectorOffsets + index%reflectorOffsets
+				for letter := range limit {
+					next[nextIndex][letter] = reverse[reflector[forward[letter]]]
+				}
+			}
+		}
+
+		reflectors = next
+	}
+
+	scrambler := &Scrambler{
+		key:    ScramblerKey(setting),
+		tables: make([]Permutation, len(reflectors)),
+	}
+
+	for index, reflector := range reflectors {
+		for letter := range limit {
+			scrambler.tables[index][letter] = setting.EntryWheel.Reverse[reflector[setting.EntryWheel.Forward[letter]]]
+		}
+	}
+
+	return scrambler, nil
+}
+
+// Fits reports whether the scrambler was built for the wheel order and wiring of the setting.
+func (what *Scrambler) Fits(setting *Setting) bool {
+	return setting != nil && what.key == ScramblerKey(setting)
+}
+
+func (what *Scrambler) Key() string {
+	return what.key
+}
+
+// Size returns the bytes taken by the permutations.
+func (what *Scrambler) Size() int {
+	return len(what.tables) * len(Permutation{})
+}
+
+// Permutation returns the scrambler permutation at the current positions of the setting.
+func (what *Scrambler) Permutation(setting *Setting) *Permutation {
+	limit := len(defs.UpperCase)
+
+	index := 0
+	for _, rotor := range setting.Rotors {
+		index = index*limit + (rotor.Position-rotor.RingSetting+limit)%limit
+	}
+
+	if setting.Reflector.Settable {
+		index = index*limit + (setting.Reflector.Position-setting.Reflector.RingSetting+limit)%limit
+	}
+
+	return &what.tables[index]
+}
+
+// Transform does the same as Setting.Transform, using the precomputed permutation.
+func (what *Scrambler) Transform(setting *Setting, in int) int {
+	in = setting.PlugBoard.Encrypt(in)
+	in = int(what.Permutation(setting)[in])
+
+	return setting.PlugBoard.Decrypt(in)
+}
+
+// ScramblerKey identifies the wiring and wheel order of the setting, settings with the same key share a scrambler.
+func ScramblerKey(setting *Setting) string {
+	var builder strings.Builder
+	builder.WriteString(setting.EntryWheel.Reverse.String())
+	for _, rotor := range setting.Rotors {
+		builder.WriteString(rotor.Forward.String())
+	}
+
+	builder.WriteString(setting.Reflector.Mapping.String())
+	if setting.Reflector.Settable {
+		builder.WriteString("+")
+	}
+
+	return builder.String()
+}
@@ -5,32 +5,95 @@ import (
 	"github.com/r3db34n1an/enigma/pkg/defs"
 	"github.com/r3db34n1an/enigma/pkg/embed"
 	"gopkg.in/yaml.v3"
+	"math/bits"
+	"slices"
 	"strings"
+	"sync"
+	"unicode"
 )
 
-var settings Settings
+var (
+	settings      Settings
+	settingsOnce  sync.Once
+	settingsError error
+)
 
 type Setting struct {
-	IDGroups  []string   // Kenngruppen
-	Rotors    RotorGroup // Walzenlage
-	Reflector Reflector  // Reflektor
-	PlugBoard PlugBoard  // Steckerverbindungen
+	Day        int        // Datum, day of the month on the key sheet
+	IDGroups   []string   // Kenngruppen
+	Machine    *Machine   // Modell
+	EntryWheel EntryWheel // Eintrittswalze
+	Rotors     RotorGroup // Walzenlage
+	Reflector  Reflector  // Reflektor
+	PlugBoard  PlugBoard  // Steckerverbindungen
+	Registry   *Registry  // components to choose from, the built-ins if nil
 }
 
 type Settings []Setting
 
-func (what *Setting) Get(name string) error {
-	if settings == nil {
-		loadError := settings.Load(embed.SettingsYaml)
+// NewBareSetting sets up the rotors of the order with ring settings at A between the entry wheel of the default machine
+// and the reflector, without machine and plug board. The rotors step as a rotor group.
+func NewBareSetting(order []string, reflectorName string) (*Setting, error) {
+	defaultMachine, machineError := GetMachine(DefaultMachine)
+	if machineError != nil {
+		return nil, machineError
+	}
+
+	entryWheel, entryWheelError := GetEntryWheel(defaultMachine.EntryWheel)
+	if entryWheelError != nil {
+		return nil, entryWheelError
+	}
+
+	reflector, reflectorError := GetReflector(reflectorName)
+	if reflectorError != nil {
+		return nil, reflectorError
+	}
+
+	setting := &Setting{
+		EntryWheel: *entryWheel,
+		Reflector:  *reflector,
+	}
+
+	for _, name := range order {
+		rotor, rotorError := GetRotor(name)
+		if rotorError != nil {
+			return nil, fmt.Errorf("invalid rotor order %v: %v", order, rotorError)
+		}
+
+		drum := *rotor
+		drum.RingSetting = 0
+		setting.Rotors = append(setting.Rotors, &drum)
+	}
+
+	return setting, nil
+}
+
+func GetSettings() (Settings, error) {
+	settingsOnce.Do(func() {
+		var newSettings Settings
+		loadError := newSettings.Load(embed.SettingsYaml)
 		if loadError != nil {
-			return fmt.Errorf("failed to load settings: %v", loadError)
+			settingsError = fmt.Errorf("failed to load settings: %v", loadError)
+			return
 		}
+
+		settings = newSettings
+	})
+
+	return settings, settingsError
+}
+
+// Get loads the setting of the key sheet day with the Kenngruppe, in either case.
+func (what *Setting) Get(name string) error {
+	allSettings, settingsError := GetSettings()
+	if settingsError != nil {
+		return settingsError
 	}
 
-	for _, setting := range settings {
+	for _, setting := range allSettings {
 		for _, idGroup := range setting.IDGroups {
-			if strings.ToUpper(name) == idGroup {
-				*what = setting
+			if strings.EqualFold(name, idGroup) {
+				*what = *setting.Copy()
 				return nil
 			}
 		}
@@ -39,22 +102,159 @@ func (what *Setting) Get(name string) error {
 	return fmt.Errorf("setting %q not found", name)
 }
 
+// GetDay loads the setting of the given day from the key sheet.
+func (what *Setting) GetDay(day int) error {
+	allSettings, settingsError := GetSettings()
+	if settingsError != nil {
+		return settingsError
+	}
+
+	for _, setting := range allSettings {
+		if setting.Day == day {
+			*what = *setting.Copy()
+			return nil
+		}
+	}
+
+	return fmt.Errorf("setting for day %d not found", day)
+}
+
+// Parse loads the setting from a key in the format the engine reads.
+func (what *Setting) Parse(key string) error {
+	var exported ExportSetting
+	parseError := exported.Parse(key)
+	if parseError != nil {
+		return parseError
+	}
+
+	return what.Import(exported)
+}
+
+// Random draws a setting from the whole keyspace of the default machine.
 func (what *Setting) Random() error {
-	if settings == nil {
-		loadError := settings.Load(embed.SettingsYaml)
-		if loadError != nil {
-			return fmt.Errorf("failed to load settings: %v", loadError)
+	generator := NewRandomGenerator()
+	generator.Registry = what.Registry
+
+	setting, generateError := generator.Next()
+	if generateError != nil {
+		return fmt.Errorf("failed to generate setting: %v", generateError)
+	}
+
+	*what = *setting
+
+	return nil
+}
+
+func (what *Setting) Step() {
+	if what.Machine == nil {
+		what.Rotors.Move()
+		return
+	}
+
+	what.Machine.Step(what)
+}
+
+// StepBack turns the rotors back to the positions they had before the last Step. Stepping is not always reversible:
+// with double stepping some positions have two predecessors, then the one turning fewer wheels wins, and some can not
+// be reached by stepping at all.
+func (what *Setting) StepBack() error {
+	current := what.wheelPositions()
+	limit := len(defs.UpperCase)
+
+	// every wheel either moved by one or stayed put, try all combinations
+	found := -1
+	for moved := range 1 << len(current) {
+		if found != -1 && bits.OnesCount(uint(moved)) >= bits.OnesCount(uint(found)) {
+			continue
+		}
+
+		candidate := what.Copy()
+		previous := slices.Clone(current)
+		for index := range previous {
+			if moved&(1<<index) != 0 {
+				previous[index] = (previous[index] - 1 + limit) % limit
+			}
+		}
+
+		candidate.setWheelPositions(previous)
+		candidate.Step()
+		if slices.Equal(candidate.wheelPositions(), current) {
+			found = moved
 		}
 	}
 
-	*what = settings[defs.RandomInt(0, len(settings)-1)]
-	for _, rotor := range what.Rotors {
-		rotor.Position = defs.RandomInt(0, 25)
+	if found == -1 {
+		return fmt.Errorf("positions can not be reached by stepping")
 	}
 
+	for index := range current {
+		if found&(1<<index) != 0 {
+			current[index] = (current[index] - 1 + limit) % limit
+		}
+	}
+
+	what.setWheelPositions(current)
+
 	return nil
 }
 
+// wheelPositions returns the positions of the rotors and of a reflector driven by them.
+func (what *Setting) wheelPositions() []int {
+	positions := make([]int, 0, len(what.Rotors)+1)
+	for _, rotor := range what.Rotors {
+		positions = append(positions, rotor.Position)
+	}
+
+	if what.Reflector.Moving {
+		positions = append(positions, what.Reflector.Position)
+	}
+
+	return positions
+}
+
+func (what *Setting) setWheelPositions(positions []int) {
+	for index, rotor := range what.Rotors {
+		rotor.Position = positions[index]
+	}
+
+	if what.Reflector.Moving {
+		what.Reflector.Position = positions[len(what.Rotors)]
+	}
+}
+
+// Components returns the chain from the keyboard up to the reflector.
+func (what *Setting) Components() []Component {
+	components := []Component{&what.PlugBoard, &what.EntryWheel}
+	for index := len(what.Rotors) - 1; index >= 0; index-- {
+		components = append(components, what.Rotors[index])
+	}
+
+	return components
+}
+
+// Transform runs the signal through the components and the reflector, the same as passing it through Components.
+func (what *Setting) Transform(in int) int {
+	in = what.PlugBoard.Encrypt(in)
+	in = what.Scramble(in)
+
+	return what.PlugBoard.Decrypt(in)
+}
+
+// Scramble runs the signal from the entry wheel to the reflector and back, leaving out the plug board.
+func (what *Setting) Scramble(in int) int {
+	in = what.EntryWheel.Encrypt(in)
+	for index := len(what.Rotors) - 1; index >= 0; index-- {
+		in = what.Rotors[index].Encrypt(in)
+	}
+
+	in = what.Reflector.Reflect(in)
+	for _, rotor := range what.Rotors {
+		in = rotor.Decrypt(in)
+	}
+
+	return what.EntryWheel.Decrypt(in)
+}
+
 func (what *Setting) Export() ExportSetting {
 	var exportedRotors []ExportRotor
 	for _, rotor := range what.Rotors {
@@ -65,16 +265,32 @@ func (what *Setting) Export() ExportSetting {
 		})
 	}
 
-	exportedPlugBoard := make(map[string]string)
-	for plug, value := range what.PlugBoard.Mapping {
-		exportedPlugBoard[string(defs.UpperCase[plug])] = string(defs.UpperCase[value])
+	var exportedUhr *ExportUhr
+	if what.PlugBoard.Uhr != nil {
+		exportedUhr = &ExportUhr{
+			Position: what.PlugBoard.Uhr.Position,
+			Plugs:    strings.Join(what.PlugBoard.Pairs, " "),
+		}
+	}
+
+	exported := ExportSetting{
+		Rotors:          exportedRotors,
+		Reflector:       what.Reflector.Name,
+		ReflectorWiring: what.Reflector.Wiring,
+		PlugBoard:       what.PlugBoard.Export(),
+		Uhr:             exportedUhr,
 	}
 
-	return ExportSetting{
-		Rotors:    exportedRotors,
-		Reflector: what.Reflector.Name,
-		PlugBoard: exportedPlugBoard,
+	if what.Machine != nil {
+		exported.Machine = what.Machine.Name
 	}
+
+	if what.Reflector.Settable {
+		exported.ReflectorPosition = string(rune(what.Reflector.Position + 'A'))
+		exported.ReflectorRingSetting = string(rune(what.Reflector.RingSetting + 'A'))
+	}
+
+	return exported
 }
 
 func (what *Setting) Import(exportSetting ExportSetting) error {
@@ -91,33 +307,135 @@ func (what *Setting) Import(exportSetting ExportSetting) error {
 		return reflectorError
 	}
 
+	if len(exportSetting.ReflectorPosition) > 0 {
+		what.Reflector.Position = strings.IndexRune(defs.UpperCase, unicode.ToUpper(rune(exportSetting.ReflectorPosition[0])))
+	}
+
+	if len(exportSetting.ReflectorRingSetting) > 0 {
+		what.Reflector.RingSetting = strings.IndexRune(defs.UpperCase, unicode.ToUpper(rune(exportSetting.ReflectorRingSetting[0])))
+	}
+
+	if len(exportSetting.ReflectorWiring) > 0 {
+		wiringError := what.Reflector.Rewire(exportSetting.ReflectorWiring, ReflectorNotation(exportSetting.ReflectorNotation))
+		if wiringError != nil {
+			return fmt.Errorf("invalid reflector wiring %q: %v", exportSetting.ReflectorWiring, wiringError)
+		}
+	}
+
 	plugBoardError := what.ImportPlugBoard(exportSetting.PlugBoard)
 	if plugBoardError != nil {
 		return plugBoardError
 	}
 
+	if exportSetting.Uhr != nil {
+		uhrError := what.ImportUhr(*exportSetting.Uhr)
+		if uhrError != nil {
+			return uhrError
+		}
+	}
+
+	machineError := what.ImportMachine(exportSetting.Machine)
+	if machineError != nil {
+		return machineError
+	}
+
 	return what.validate(true)
 }
 
+// ImportMachine sets the named machine, without a name the first machine accepting the setting is used.
+func (what *Setting) ImportMachine(exportMachine string) error {
+	registry, registryError := what.registry()
+	if registryError != nil {
+		return registryError
+	}
+
+	if exportMachine != "" {
+		machine, machineError := registry.GetMachine(exportMachine)
+		if machineError != nil {
+			return fmt.Errorf("invalid machine %q: %v", exportMachine, machineError)
+		}
+
+		return what.useMachine(machine)
+	}
+
+	var machineNames []string
+	for _, machine := range registry.GetMachines() {
+		if what.useMachine(machine) == nil && machine.Validate(what) == nil {
+			return nil
+		}
+
+		machineNames = append(machineNames, machine.Name)
+	}
+
+	what.Machine = nil
+	return fmt.Errorf("setting does not match any machine of %v", machineNames)
+}
+
+func (what *Setting) useMachine(machine *Machine) error {
+	registry, registryError := what.registry()
+	if registryError != nil {
+		return registryError
+	}
+
+	entryWheel, entryWheelError := registry.GetEntryWheel(machine.EntryWheel)
+	if entryWheelError != nil {
+		return fmt.Errorf("invalid entry wheel %q: %v", machine.EntryWheel, entryWheelError)
+	}
+
+	what.Machine = machine
+	what.EntryWheel = *entryWheel
+
+	return nil
+}
+
 func (what *Setting) ImportRotor(exportRotor ExportRotor) error {
-	rotor, rotorError := GetRotor(strings.ToUpper(exportRotor.Name))
+	registry, registryError := what.registry()
+	if registryError != nil {
+		return registryError
+	}
+
+	rotor, rotorError := registry.GetRotor(strings.ToUpper(exportRotor.Name))
 	if rotorError != nil {
 		return fmt.Errorf("invalid rotor %q: %v", exportRotor.Name, rotorError)
 	}
 
-	exportRotor.Position = strings.ToUpper(exportRotor.Position)
-	exportRotor.RingSetting = strings.ToUpper(exportRotor.RingSetting)
+	var letterError error
+	rotor.Position, letterError = importLetter(exportRotor.Position)
+	if letterError != nil {
+		return fmt.Errorf("invalid rotor %q position: %v", exportRotor.Name, letterError)
+	}
 
-	rotor.Position = strings.IndexRune(defs.UpperCase, rune(exportRotor.Position[0]))
-	rotor.RingSetting = strings.IndexRune(defs.UpperCase, rune(exportRotor.RingSetting[0]))
+	rotor.RingSetting, letterError = importLetter(exportRotor.RingSetting)
+	if letterError != nil {
+		return fmt.Errorf("invalid rotor %q ring setting: %v", exportRotor.Name, letterError)
+	}
 
 	what.Rotors = append(what.Rotors, rotor)
 
 	return nil
 }
 
+// importLetter reads a position or ring setting, an empty value is the first letter.
+func importLetter(value string) (int, error) {
+	if len(value) == 0 {
+		return 0, nil
+	}
+
+	letter := strings.Index(defs.UpperCase, strings.ToUpper(value))
+	if len(value) != 1 || letter == -1 {
+		return 0, fmt.Errorf("invalid letter %q", value)
+	}
+
+	return letter, nil
+}
+
 func (what *Setting) ImportReflector(exportReflector string) error {
-	reflector, reflectorError := GetReflector(exportReflector)
+	registry, registryError := what.registry()
+	if registryError != nil {
+		return registryError
+	}
+
+	reflector, reflectorError := registry.GetReflector(exportReflector)
 	if reflectorError != nil {
 		return fmt.Errorf("invalid reflector %q: %v", exportReflector, reflectorError)
 	}
@@ -128,25 +446,64 @@ func (what *Setting) ImportReflector(exportReflector string) error {
 }
 
 func (what *Setting) ImportPlugBoard(exportPlugBoard ExportPlugBoard) error {
-	what.PlugBoard.Mapping = make(map[int]int)
+	what.PlugBoard = PlugBoard{}
+	what.PlugBoard.Reset()
+
+	for plug, value := range exportPlugBoard {
+		plugIndex := strings.IndexRune(defs.UpperCase, unicode.ToUpper(rune(plug[0])))
+		valueIndex := strings.IndexRune(defs.UpperCase, unicode.ToUpper(rune(value[0])))
 
-	if exportPlugBoard == nil {
-		return nil
+		connectError := what.PlugBoard.Connect(plugIndex, valueIndex)
+		if connectError != nil {
+			return connectError
+		}
 	}
 
-	for plug, value := range exportPlugBoard {
-		plugIndex := strings.IndexRune(defs.UpperCase, rune(plug[0]))
-		valueIndex := strings.IndexRune(defs.UpperCase, rune(value[0]))
+	return nil
+}
 
-		what.PlugBoard.Mapping[plugIndex] = valueIndex
-		what.PlugBoard.Mapping[valueIndex] = plugIndex
+func (what *Setting) ImportUhr(exportUhr ExportUhr) error {
+	parseError := what.PlugBoard.Parse(exportUhr.Plugs)
+	if parseError != nil {
+		return fmt.Errorf("invalid uhr plugs %q: %v", exportUhr.Plugs, parseError)
+	}
+
+	uhrError := what.PlugBoard.SetUhr(exportUhr.Position)
+	if uhrError != nil {
+		return fmt.Errorf("invalid uhr: %v", uhrError)
 	}
 
 	return nil
 }
 
+// Copy returns a deep copy of the setting, stepping the copy leaves the original untouched.
+func (what *Setting) Copy() *Setting {
+	newSetting := *what
+	newSetting.IDGroups = slices.Clone(what.IDGroups)
+	newSetting.Rotors = make(RotorGroup, 0, len(what.Rotors))
+	for _, rotor := range what.Rotors {
+		if rotor == nil {
+			newSetting.Rotors = append(newSetting.Rotors, nil)
+			continue
+		}
+
+		newRotor := *rotor
+		newSetting.Rotors = append(newSetting.Rotors, &newRotor)
+	}
+
+	if what.PlugBoard.Uhr != nil {
+		newUhr := *what.PlugBoard.Uhr
+		newSetting.PlugBoard.Uhr = &newUhr
+	}
+
+	return &newSetting
+}
+
 func (what *Setting) Clone() (*Setting, error) {
-	var setting Setting
+	setting := Setting{
+		Registry: what.Registry,
+	}
+
 	importError := setting.Import(what.Export())
 	if importError != nil {
 		return nil, fmt.Errorf("failed to clone setting: %v", importError)
@@ -156,10 +513,20 @@ func (what *Setting) Clone() (*Setting, error) {
 }
 
 func (what *Setting) Load(data any) error {
+	var machine, reflectorWiring, reflectorNotation, reflectorPosition, reflectorRingSetting, uhrPosition any
 	switch castData := data.(type) {
 	case map[string]any:
 		for key, value := range castData {
 			switch strings.ToLower(key) {
+			case "machine":
+				machine = value
+
+			case "day":
+				importError := what.LoadDay(value)
+				if importError != nil {
+					return fmt.Errorf("invalid day: %v", importError)
+				}
+
 			case "id_groups":
 				importError := what.LoadIDGroups(value)
 				if importError != nil {
@@ -178,12 +545,27 @@ func (what *Setting) Load(data any) error {
 					return fmt.Errorf("invalid reflector: %v", importError)
 				}
 
+			case "reflector_position":
+				reflectorPosition = value
+
+			case "reflector_ring_setting":
+				reflectorRingSetting = value
+
+			case "reflector_wiring":
+				reflectorWiring = value
+
+			case "reflector_notation":
+				reflectorNotation = value
+
 			case "plug_board":
 				importError := what.LoadPlugBoard(value)
 				if importError != nil {
 					return fmt.Errorf("invalid plug_board: %v", importError)
 				}
 
+			case "uhr":
+				uhrPosition = value
+
 			default:
 				return fmt.Errorf("invalid setting key %q", key)
 			}
@@ -193,9 +575,64 @@ func (what *Setting) Load(data any) error {
 		return fmt.Errorf("invalid setting format %T, expected map[string]any", data)
 	}
 
+	if reflectorPosition != nil || reflectorRingSetting != nil {
+		importError := what.LoadReflectorSetting(reflectorPosition, reflectorRingSetting)
+		if importError != nil {
+			return fmt.Errorf("invalid reflector setting: %v", importError)
+		}
+	}
+
+	if reflectorWiring != nil {
+		importError := what.LoadReflectorWiring(reflectorWiring, reflectorNotation)
+		if importError != nil {
+			return fmt.Errorf("invalid reflector_wiring: %v", importError)
+		}
+	}
+
+	if uhrPosition != nil {
+		importError := what.LoadUhr(uhrPosition)
+		if importError != nil {
+			return fmt.Errorf("invalid uhr: %v", importError)
+		}
+	}
+
+	importError := what.LoadMachine(machine)
+	if importError != nil {
+		return fmt.Errorf("invalid machine: %v", importError)
+	}
+
 	return what.validate(false)
 }
 
+func (what *Setting) LoadMachine(value any) error {
+	switch castValue := value.(type) {
+	case nil:
+		return what.ImportMachine("")
+
+	case string:
+		return what.ImportMachine(castValue)
+
+	default:
+		return fmt.Errorf("invalid machine %T, expected string", value)
+	}
+}
+
+func (what *Setting) LoadDay(value any) error {
+	switch castValue := value.(type) {
+	case int:
+		if castValue < 1 || castValue > 31 {
+			return fmt.Errorf("invalid day %d, expected 1-31", castValue)
+		}
+
+		what.Day = castValue
+
+	default:
+		return fmt.Errorf("invalid day format %T, expected int", value)
+	}
+
+	return nil
+}
+
 func (what *Setting) LoadIDGroups(value any) error {
 	switch castValue := value.(type) {
 	case []any:
@@ -234,8 +671,13 @@ func (what *Setting) LoadRotors(value any) error {
 					return fmt.Errorf("invalid rotor %q, expected single item", castRotorMap)
 				}
 
+				registry, registryError := what.registry()
+				if registryError != nil {
+					return registryError
+				}
+
 				for rotorName, rotorValue := range castRotorMap {
-					rotor, rotorError := GetRotor(rotorName)
+					rotor, rotorError := registry.GetRotor(rotorName)
 					if rotorError != nil {
 						return fmt.Errorf("invalid rotor %q: %v", rotorName, rotorError)
 					}
@@ -266,7 +708,12 @@ func (what *Setting) LoadRotors(value any) error {
 func (what *Setting) LoadReflector(value any) error {
 	switch castValue := value.(type) {
 	case string:
-		reflector, reflectorError := GetReflector(castValue)
+		registry, registryError := what.registry()
+		if registryError != nil {
+			return registryError
+		}
+
+		reflector, reflectorError := registry.GetReflector(castValue)
 		if reflectorError != nil {
 			return fmt.Errorf("invalid reflector %q: %v", castValue, reflectorError)
 		}
@@ -280,8 +727,56 @@ func (what *Setting) LoadReflector(value any) error {
 	return nil
 }
 
+func (what *Setting) LoadReflectorSetting(position any, ringSetting any) error {
+	if position != nil {
+		switch castPosition := position.(type) {
+		case string:
+			if len(castPosition) != 1 {
+				return fmt.Errorf("invalid reflector position %q, expected 1 character", castPosition)
+			}
+
+			what.Reflector.Position = strings.IndexRune(defs.UpperCase, unicode.ToUpper(rune(castPosition[0])))
+
+		case int:
+			what.Reflector.Position = castPosition - 1
+
+		default:
+			return fmt.Errorf("invalid reflector position %T, expected int or string", position)
+		}
+	}
+
+	if ringSetting != nil {
+		switch castRingSetting := ringSetting.(type) {
+		case int:
+			what.Reflector.RingSetting = castRingSetting - 1
+
+		default:
+			return fmt.Errorf("invalid reflector ring %T, expected int", ringSetting)
+		}
+	}
+
+	return nil
+}
+
+func (what *Setting) LoadReflectorWiring(value any, notation any) error {
+	castValue, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("invalid reflector wiring %T, expected string", value)
+	}
+
+	castNotation := ""
+	if notation != nil {
+		castNotation, ok = notation.(string)
+		if !ok {
+			return fmt.Errorf("invalid reflector notation %T, expected string", notation)
+		}
+	}
+
+	return what.Reflector.Rewire(castValue, ReflectorNotation(castNotation))
+}
+
 func (what *Setting) LoadPlugBoard(value any) error {
-	what.PlugBoard.Mapping = make(map[int]int)
+	what.PlugBoard.Reset()
 
 	switch castValue := value.(type) {
 	case string:
@@ -297,6 +792,24 @@ func (what *Setting) LoadPlugBoard(value any) error {
 	return nil
 }
 
+func (what *Setting) LoadUhr(value any) error {
+	switch castValue := value.(type) {
+	case int:
+		return what.PlugBoard.SetUhr(castValue)
+
+	default:
+		return fmt.Errorf("invalid uhr %T, expected int", value)
+	}
+}
+
+func (what *Setting) registry() (*Registry, error) {
+	if what.Registry != nil {
+		return what.Registry, nil
+	}
+
+	return DefaultRegistry()
+}
+
 func (what *Setting) validate(imported bool) error {
 	// Validate IDGroups
 	if !imported {
@@ -325,66 +838,49 @@ func (what *Setting) validate(imported bool) error {
 			return fmt.Errorf("invalid rotor ring %d, expected 0-25", rotor.RingSetting)
 		}
 
-		if rotor.Forward == nil {
-			return fmt.Errorf("invalid rotor mapping: nil")
+		if rotor.Position < 0 || rotor.Position > 25 {
+			return fmt.Errorf("invalid rotor position %d, expected 0-25", rotor.Position)
 		}
 
-		if len(rotor.Forward) != 26 {
-			return fmt.Errorf("invalid rotor mapping length %d, expected 26", len(rotor.Forward))
-		}
-
-		for index, value := range rotor.Forward {
-			if value < 0 || value > 25 {
-				return fmt.Errorf("invalid rotor mapping value %d, expected 0-25", value)
-			}
-
-			if _, exists := rotor.Forward[index]; !exists {
-				return fmt.Errorf("invalid rotor mapping index %d, expected 0-25", index)
-			}
-		}
-
-		if rotor.Reverse == nil {
-			return fmt.Errorf("invalid rotor mapping: nil")
-		}
-
-		if len(rotor.Reverse) != 26 {
-			return fmt.Errorf("invalid rotor mapping length %d, expected 26", len(rotor.Reverse))
+		rotorError := rotor.validate()
+		if rotorError != nil {
+			return rotorError
 		}
+	}
 
-		for index, value := range rotor.Reverse {
-			if value < 0 || value > 25 {
-				return fmt.Errorf("invalid rotor mapping value %d, expected 0-25", value)
-			}
+	// Validate Reflector
+	reflectorError := what.Reflector.validate()
+	if reflectorError != nil {
+		return fmt.Errorf("invalid reflector: %v", reflectorError)
+	}
 
-			if _, exists := rotor.Reverse[index]; !exists {
-				return fmt.Errorf("invalid rotor mapping index %d, expected 0-25", index)
-			}
-		}
+	// Validate EntryWheel
+	if !what.EntryWheel.Forward.IsValid() || what.EntryWheel.Reverse != what.EntryWheel.Forward.Inverse() {
+		return fmt.Errorf("invalid entry wheel mapping %q, expected a permutation", what.EntryWheel.Reverse.String())
 	}
 
-	// Validate Reflector
-	if len(what.Reflector.Mapping) != 26 {
-		return fmt.Errorf("invalid reflector mapping length %d, expected 26", len(what.Reflector.Mapping))
+	// Validate PlugBoard
+	if what.PlugBoard.wired && !what.PlugBoard.Mapping.IsInvolution() {
+		return fmt.Errorf("invalid plug board %q, expected pairs of letters", what.PlugBoard.Mapping.String())
 	}
 
-	for index, value := range what.Reflector.Mapping {
-		if value < 0 || value > 25 {
-			return fmt.Errorf("invalid reflector mapping value %d, expected 0-25", value)
-		}
+	// Validate Machine
+	if what.Machine == nil {
+		return fmt.Errorf("invalid machine: nil")
+	}
 
-		if _, exists := (what.Reflector.Mapping)[index]; !exists {
-			return fmt.Errorf("invalid reflector mapping index %d, expected 0-25", index)
-		}
+	machineError := what.Machine.Validate(what)
+	if machineError != nil {
+		return machineError
 	}
 
-	// Validate PlugBoard
-	for index, value := range what.PlugBoard.Mapping {
-		if value < 0 || value > 25 {
-			return fmt.Errorf("invalid plug board value %d, expected 0-25", value)
+	if what.PlugBoard.Uhr != nil {
+		if len(what.PlugBoard.Pairs) != UhrPairs {
+			return fmt.Errorf("invalid uhr plug pairs %d, expected %d", len(what.PlugBoard.Pairs), UhrPairs)
 		}
 
-		if _, exists := what.PlugBoard.Mapping[index]; !exists {
-			return fmt.Errorf("invalid plug board forward index %d, expected 0-25", index)
+		if !what.PlugBoard.forward.IsValid() || what.PlugBoard.reverse != what.PlugBoard.forward.Inverse() {
+			return fmt.Errorf("invalid uhr mapping %q", what.PlugBoard.forward.String())
 		}
 	}
 
@@ -392,14 +888,30 @@ func (what *Setting) validate(imported bool) error {
 }
 
 func (what *Settings) Load(data any) error {
+	return what.LoadWithRegistry(data, nil)
+}
+
+// LoadWithRegistry reads the settings with the components of the registry, the built-ins if nil.
+func (what *Settings) LoadWithRegistry(data any, registry *Registry) error {
 	var items []any
 	parseError := yaml.Unmarshal(data.([]byte), &items)
 	if parseError != nil {
 		return fmt.Errorf("failed to parse settings: %v", parseError)
 	}
 
+	return what.loadRegistryItems(items, registry)
+}
+
+func (what *Settings) loadItems(items []any) error {
+	return what.loadRegistryItems(items, nil)
+}
+
+func (what *Settings) loadRegistryItems(items []any, registry *Registry) error {
 	for _, settingData := range items {
-		setting := new(Setting)
+		setting := &Setting{
+			Registry: registry,
+		}
+
 		settingError := setting.Load(settingData)
 		if settingError != nil {
 			return settingError
@@ -0,0 +1,27 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Stepping string
+
+const (
+	RatchetStepping Stepping = "ratchet" // pawls and notches, with double stepping
+	GearStepping    Stepping = "gear"    // cog wheels, like an odometer
+	NoStepping      Stepping = "none"
+)
+
+func ParseStepping(name string) (Stepping, error) {
+	switch stepping := Stepping(strings.ToLower(name)); stepping {
+	case "":
+		return RatchetStepping, nil
+
+	case RatchetStepping, GearStepping, NoStepping:
+		return stepping, nil
+
+	default:
+		return "", fmt.Errorf("invalid stepping %q", name)
+	}
+}
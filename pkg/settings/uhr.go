@@ -0,0 +1,134 @@
+package settings
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"github.com/r3db34n1an/enigma/pkg/embed"
+	"gopkg.in/yaml.v3"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	UhrPairs     = 10
+	UhrPositions = 40
+)
+
+var (
+	uhr      *Uhr
+	uhrOnce  sync.Once
+	uhrError error
+)
+
+type Uhr struct {
+	Position int   // Uhrstellung
+	Wiring   []int // a-side contact to b-side contact
+	BPlugs   []int // contact group of the b-plug of each pair
+}
+
+func GetUhr() (*Uhr, error) {
+	uhrOnce.Do(func() {
+		newUhr := new(Uhr)
+		loadError := newUhr.load(embed.UhrYaml)
+		if loadError != nil {
+			uhrError = fmt.Errorf("failed to load uhr: %v", loadError)
+			return
+		}
+
+		uhr = newUhr
+	})
+
+	if uhrError != nil {
+		return nil, uhrError
+	}
+
+	newUhr := *uhr
+	return &newUhr, nil
+}
+
+func (what *Uhr) SetPosition(position int) error {
+	if position < 0 || position >= UhrPositions {
+		return fmt.Errorf("invalid uhr position %d, expected 0-%d", position, UhrPositions-1)
+	}
+
+	what.Position = position
+	return nil
+}
+
+// Mapping returns the non-reciprocal substitution for the given plug pairs, the first letter of each pair holds the
+// red a-plug, the second the white b-plug.
+func (what *Uhr) Mapping(pairs []string) (Permutation, error) {
+	mapping := IdentityPermutation()
+	if len(pairs) != UhrPairs {
+		return mapping, fmt.Errorf("invalid uhr plug pairs %d, expected %d", len(pairs), UhrPairs)
+	}
+
+	inverse := make([]int, UhrPositions)
+	for index, value := range what.Wiring {
+		inverse[value] = index
+	}
+
+	redLetters := make([]int, UhrPairs)
+	whiteLetters := make([]int, UhrPairs)
+	for index, pair := range pairs {
+		redLetters[index] = strings.IndexRune(defs.UpperCase, rune(pair[0]))
+		whiteLetters[what.BPlugs[index]] = strings.IndexRune(defs.UpperCase, rune(pair[1]))
+	}
+
+	for index := range pairs {
+		// the large pin of the red plug leaves the disc at the small pin of a white plug and vice versa
+		redOut := (what.Wiring[(4*index+what.Position)%UhrPositions] - what.Position + UhrPositions) % UhrPositions
+		whiteOut := (inverse[(4*what.BPlugs[index]+what.Position)%UhrPositions] - what.Position + UhrPositions) % UhrPositions
+		if redOut%4 != 2 || whiteOut%4 != 2 {
+			return mapping, fmt.Errorf("invalid uhr wiring at position %d", what.Position)
+		}
+
+		mapping[redLetters[index]] = uint8(whiteLetters[redOut/4])
+		mapping[whiteLetters[what.BPlugs[index]]] = uint8(redLetters[whiteOut/4])
+	}
+
+	return mapping, nil
+}
+
+func (what *Uhr) load(data any) error {
+	castData, ok := data.([]byte)
+	if !ok {
+		return fmt.Errorf("invalid uhr data, expected []byte, got %T", data)
+	}
+
+	var items struct {
+		Wiring []int `yaml:"wiring"`
+		BPlugs []int `yaml:"b_plugs"`
+	}
+
+	parseError := yaml.Unmarshal(castData, &items)
+	if parseError != nil {
+		return fmt.Errorf("failed to parse uhr: %v", parseError)
+	}
+
+	if len(items.Wiring) != UhrPositions {
+		return fmt.Errorf("invalid uhr wiring length %d, expected %d", len(items.Wiring), UhrPositions)
+	}
+
+	for index := range UhrPositions {
+		if !slices.Contains(items.Wiring, index) {
+			return fmt.Errorf("invalid uhr wiring, contact %d is not connected", index)
+		}
+	}
+
+	if len(items.BPlugs) != UhrPairs {
+		return fmt.Errorf("invalid uhr b_plugs length %d, expected %d", len(items.BPlugs), UhrPairs)
+	}
+
+	for index := range UhrPairs {
+		if !slices.Contains(items.BPlugs, index) {
+			return fmt.Errorf("invalid uhr b_plugs, contact group %d is not used", index)
+		}
+	}
+
+	what.Wiring = items.Wiring
+	what.BPlugs = items.BPlugs
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package zygalski
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// span is the number of rows and columns printed: the 26 positions and 25 again, so the sheets can be shifted over
+// each other and still cover a full square.
+const span = 2*26 - 1
+
+var (
+	sheetColor = color.RGBA{R: 0xe8, G: 0xdc, B: 0xb8, A: 0xff}
+	holeColor  = color.RGBA{A: 0xff}
+)
+
+// String writes the sheet as data: a title line, then one row per middle rotor offset with a character per right rotor
+// offset, the bits of the pairs as a digit or a dot for none.
+func (what *Sheet) String() string {
+	var builder strings.Builder
+	_, _ = fmt.Fprintf(&builder, "%v %v %c\n", strings.Join(what.Order, "-"), what.Reflector, defs.UpperCase[what.Left])
+	builder.WriteString("  " + defs.UpperCase + "\n")
+	for middle, row := range what.Females {
+		builder.WriteByte(defs.UpperCase[middle])
+		builder.WriteByte(' ')
+		for _, females := range row {
+			if females == 0 {
+				builder.WriteByte('.')
+			} else {
+				builder.WriteByte('0' + females)
+			}
+		}
+
+		builder.WriteByte('\n')
+	}
+
+	return builder.String()
+}
+
+// SVG draws the sheet with a hole for every position that can give a female in the pair, repeated like the paper
+// sheets, with the middle rotor offsets down and the right rotor offsets across. cell is the size of a position.
+func (what *Sheet) SVG(writer io.Writer, pair int, cell int) error {
+	if pair < 0 || pair >= Pairs {
+		return fmt.Errorf("invalid pair %d", pair)
+	}
+
+	if cell < 4 {
+		return fmt.Errorf("invalid cell size %d, expected at least 4", cell)
+	}
+
+	size := (span + 1) * cell
+	buffered := bufio.NewWriter(writer)
+	_, _ = fmt.Fprintf(buffered, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", size, size, size, size)
+	_, _ = fmt.Fprintf(buffered, "<title>%v %v %c %d-%d</title>\n", strings.Join(what.Order, "-"), what.Reflector, defs.UpperCase[what.Left], pair+1, pair+1+Pairs)
+	_, _ = fmt.Fprintf(buffered, `<rect width="%d" height="%d" fill="#e8dcb8"/>`+"\n", size, size)
+	_, _ = fmt.Fprintf(buffered, `<text x="%d" y="%d" font-family="monospace" font-size="%d" text-anchor="middle" dominant-baseline="central">%c</text>`+"\n", cell/2, cell/2, cell*3/4, defs.UpperCase[what.Left])
+
+	for index := range span {
+		letter := defs.UpperCase[index%26]
+		offset := (index+1)*cell + cell/2
+		_, _ = fmt.Fprintf(buffered, `<text x="%d" y="%d" font-family="monospace" font-size="%d" text-anchor="middle" dominant-baseline="central">%c</text>`+"\n", offset, cell/2, cell*3/4, letter)
+		_, _ = fmt.Fprintf(buffered, `<text x="%d" y="%d" font-family="monospace" font-size="%d" text-anchor="middle" dominant-baseline="central">%c</text>`+"\n", cell/2, offset, cell*3/4, letter)
+	}
+
+	for row := range span {
+		for column := range span {
+			if what.Female(row%26, column%26, pair) {
+				_, _ = fmt.Fprintf(buffered, `<rect x="%d" y="%d" width="%d" height="%d"/>`+"\n", (column+1)*cell+1, (row+1)*cell+1, cell-2, cell-2)
+			}
+		}
+	}
+
+	_, _ = fmt.Fprintln(buffered, "</svg>")
+
+	flushError := buffered.Flush()
+	if flushError != nil {
+		return fmt.Errorf("failed to write sheet: %v", flushError)
+	}
+
+	return nil
+}
+
+// PNG draws the sheet like SVG, without the letters.
+func (what *Sheet) PNG(writer io.Writer, pair int, cell int) error {
+	if pair < 0 || pair >= Pairs {
+		return fmt.Errorf("invalid pair %d", pair)
+	}
+
+	if cell < 1 {
+		return fmt.Errorf("invalid cell size %d, expected at least 1", cell)
+	}
+
+	picture := image.NewPaletted(image.Rect(0, 0, span*cell, span*cell), color.Palette{sheetColor, holeColor})
+	for row := range span {
+		for column := range span {
+			if !what.Female(row%26, column%26, pair) {
+				continue
+			}
+
+			for y := row * cell; y < (row+1)*cell; y++ {
+				for x := column * cell; x < (column+1)*cell; x++ {
+					picture.SetColorIndex(x, y, 1)
+				}
+			}
+		}
+	}
+
+	encodeError := png.Encode(writer, picture)
+	if encodeError != nil {
+		return fmt.Errorf("failed to write sheet: %v", encodeError)
+	}
+
+	return nil
+}
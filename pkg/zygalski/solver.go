@@ -0,0 +1,174 @@
+package zygalski
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/defs"
+	"github.com/r3db34n1an/enigma/pkg/procedure"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"slices"
+	"strings"
+)
+
+// Female is an indicator that repeats a letter in a pair, sent after its Grundstellung.
+type Female struct {
+	Grundstellung string
+	Pair          int // 0 for 1-4, 1 for 2-5, 2 for 3-6
+}
+
+// Solution is a rotor order and ring settings, with the number of females its sheets let through.
+type Solution struct {
+	Order        []string
+	Reflector    string
+	RingSettings string
+	Matches      int
+}
+
+// FindFemales collects the females of a day's messages, a message may have several.
+func FindFemales(messages []*procedure.IndicatorMessage) ([]Female, error) {
+	var females []Female
+	for _, message := range messages {
+		grundstellung := strings.ToUpper(message.Grundstellung)
+		indicator := strings.ToUpper(message.Indicator)
+		if len(grundstellung) != 3 || strings.Trim(grundstellung, defs.UpperCase) != "" {
+			return nil, fmt.Errorf("invalid Grundstellung %q, expected 3 letters", message.Grundstellung)
+		}
+
+		if len(indicator) != 2*Pairs || strings.Trim(indicator, defs.UpperCase) != "" {
+			return nil, fmt.Errorf("invalid indicator %q, expected %d letters", message.Indicator, 2*Pairs)
+		}
+
+		for pair := range Pairs {
+			if indicator[pair] == indicator[pair+Pairs] {
+				females = append(females, Female{Grundstellung: grundstellung, Pair: pair})
+			}
+		}
+	}
+
+	return females, nil
+}
+
+// Solve stacks the sheets for every rotor order and ring setting: each female puts the sheet of its left rotor offset
+// on the pile, shifted by its Grundstellung, and a solution is where light shines through all of them. Females whose
+// Grundstellung turns the middle rotor during the indicator are left out of the rotor order, the sheets do not hold
+// for them. Up to misses sheets may cover the hole, for wrong traffic. The best solutions come first.
+func (what *Sheets) Solve(females []Female, misses int) ([]Solution, error) {
+	if len(females) == 0 {
+		return nil, fmt.Errorf("no females")
+	}
+
+	// the Grundstellungen as offsets
+	starts := make([][3]int, len(females))
+	for index, female := range females {
+		if female.Pair < 0 || female.Pair >= Pairs {
+			return nil, fmt.Errorf("invalid pair %d", female.Pair)
+		}
+
+		if len(female.Grundstellung) != 3 {
+			return nil, fmt.Errorf("invalid Grundstellung %q, expected 3 letters", female.Grundstellung)
+		}
+
+		for rotor := range starts[index] {
+			starts[index][rotor] = strings.IndexByte(defs.UpperCase, strings.ToUpper(female.Grundstellung)[rotor])
+			if starts[index][rotor] < 0 {
+				return nil, fmt.Errorf("invalid Grundstellung %q", female.Grundstellung)
+			}
+		}
+	}
+
+	var solutions []Solution
+	for _, order := range what.Orders {
+		sheets := what.sheets[strings.Join(order, "-")]
+		steady, steadyError := what.steady(order, starts)
+		if steadyError != nil {
+			return nil, steadyError
+		}
+
+		for rings := range settings.RotorPositions {
+			left, middle, right := rings/676, rings/26%26, rings%26
+
+			matches, missed := 0, 0
+			for index, female := range females {
+				if !steady[index] {
+					continue
+				}
+
+				sheet := &sheets[(starts[index][0]-left+26)%26]
+				if sheet.Female((starts[index][1]-middle+26)%26, (starts[index][2]-right+26)%26, female.Pair) {
+					matches++
+					continue
+				}
+
+				missed++
+				if missed > misses {
+					break
+				}
+			}
+
+			if missed <= misses && matches > 0 {
+				solutions = append(solutions, Solution{
+					Order:        order,
+					Reflector:    what.Reflector,
+					RingSettings: string([]byte{defs.UpperCase[left], defs.UpperCase[middle], defs.UpperCase[right]}),
+					Matches:      matches,
+				})
+			}
+		}
+	}
+
+	slices.SortStableFunc(solutions, func(one Solution, two Solution) int {
+		return two.Matches - one.Matches
+	})
+
+	return solutions, nil
+}
+
+// steady tells for every Grundstellung whether the left and middle rotor of the order stay put while the indicator
+// is typed. The turnovers depend on the windows alone, not on the ring settings.
+func (what *Sheets) steady(order []string, starts [][3]int) ([]bool, error) {
+	setting, settingError := settings.NewBareSetting(order, what.Reflector)
+	if settingError != nil {
+		return nil, settingError
+	}
+
+	steady := make([]bool, len(starts))
+	for index, start := range starts {
+		for rotor := range start {
+			setting.Rotors[rotor].Position = start[rotor]
+		}
+
+		for range 2 * Pairs {
+			setting.Rotors.Move()
+		}
+
+		steady[index] = setting.Rotors[0].Position == start[0] && setting.Rotors[1].Position == start[1]
+	}
+
+	return steady, nil
+}
+
+// Setting returns the solution as a setting without plugs, at the positions.
+func (what Solution) Setting(positions string) (settings.ExportSetting, error) {
+	exported := settings.ExportSetting{
+		Machine:   settings.DefaultMachine,
+		Reflector: what.Reflector,
+		PlugBoard: make(settings.ExportPlugBoard),
+	}
+
+	if len(positions) != len(what.Order) {
+		return exported, fmt.Errorf("invalid positions %q, expected %d letters", positions, len(what.Order))
+	}
+
+	for index, name := range what.Order {
+		exported.Rotors = append(exported.Rotors, settings.ExportRotor{
+			Name:        name,
+			Position:    strings.ToUpper(positions[index : index+1]),
+			RingSetting: what.RingSettings[index : index+1],
+		})
+	}
+
+	return exported, nil
+}
+
+func (what Solution) String() string {
+	return fmt.Sprintf("%v %v rings %v, %d females", strings.Join(what.Order, "-"), what.Reflector, what.RingSettings, what.Matches)
+}
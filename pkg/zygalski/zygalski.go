@@ -0,0 +1,165 @@
+// Package zygalski makes the perforated sheets of Henryk Zygalski and stacks them against the females of a day's
+// doubled indicators, the Polish method after the Grundstellung went into the clear on 15 September 1938.
+package zygalski
+
+import (
+	"fmt"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Pairs are the places of an indicator that repeat a letter of the message key: 1-4, 2-5 and 3-6.
+const Pairs = 3
+
+// Sheet holds the positions of a rotor order and left rotor offset that can give a female, an indicator with the same
+// letter in both places of a pair. The positions are rotor offsets, window less ring setting, so the sheet is the same
+// for every ring setting. Like the paper sheets they assume only the right rotor moves while the indicator is typed.
+type Sheet struct {
+	Order     []string
+	Reflector string
+	Left      int           // offset of the left rotor
+	Females   [26][26]uint8 // by middle and right offset, bit n is set when pair n can give a female
+}
+
+// Sheets is a set of sheets, 26 for every rotor order.
+type Sheets struct {
+	Reflector string
+	Orders    [][]string // by name
+	sheets    map[string]*[26]Sheet
+}
+
+// NewSheets makes the sheets of every order of three different rotors, on the number of CPUs. The rotors default to
+// settings.DefaultRotors and the reflector to settings.DefaultReflector.
+func NewSheets(rotors []string, reflector string) (*Sheets, error) {
+	if len(rotors) == 0 {
+		rotors = settings.DefaultRotors
+	}
+
+	if len(reflector) == 0 {
+		reflector = settings.DefaultReflector
+	}
+
+	what := &Sheets{
+		Reflector: reflector,
+		Orders:    settings.RotorOrders(rotors),
+		sheets:    make(map[string]*[26]Sheet),
+	}
+
+	if len(what.Orders) == 0 {
+		return nil, fmt.Errorf("no rotor orders, expected at least three rotors")
+	}
+
+	slices.SortFunc(what.Orders, func(one []string, two []string) int {
+		return strings.Compare(strings.Join(one, "-"), strings.Join(two, "-"))
+	})
+
+	var lock sync.Mutex
+	var firstError error
+	var waitGroup sync.WaitGroup
+	queue := make(chan []string)
+	for range min(runtime.NumCPU(), len(what.Orders)) {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for order := range queue {
+				sheets, sheetsError := newOrderSheets(order, reflector)
+
+				lock.Lock()
+				if sheetsError != nil && firstError == nil {
+					firstError = sheetsError
+				}
+
+				what.sheets[strings.Join(order, "-")] = sheets
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for _, order := range what.Orders {
+		queue <- order
+	}
+
+	close(queue)
+	waitGroup.Wait()
+
+	if firstError != nil {
+		return nil, firstError
+	}
+
+	return what, nil
+}
+
+// Sheet returns the sheet of the rotor order for the left rotor offset.
+func (what *Sheets) Sheet(order []string, left int) (*Sheet, error) {
+	sheets, found := what.sheets[strings.Join(order, "-")]
+	if !found {
+		return nil, fmt.Errorf("no sheets for rotor order %v", order)
+	}
+
+	if left < 0 || left >= len(sheets) {
+		return nil, fmt.Errorf("invalid left rotor offset %d", left)
+	}
+
+	return &sheets[left], nil
+}
+
+// Female reports whether the pair can give a female at the offsets.
+func (what *Sheet) Female(middle int, right int, pair int) bool {
+	return what.Females[middle][right]&(1<<pair) != 0
+}
+
+// Holes returns the share of positions that can give a female in the pair, about 0.4.
+func (what *Sheet) Holes(pair int) float64 {
+	count := 0
+	for middle := range what.Females {
+		for right := range what.Females[middle] {
+			if what.Female(middle, right, pair) {
+				count++
+			}
+		}
+	}
+
+	return float64(count) / float64(len(what.Females)*len(what.Females[0]))
+}
+
+// newOrderSheets runs the rotor order through every start position. The right rotor steps before every letter, the
+// indicator is typed at the six positions after the start, and a pair can give a female when the product of its two
+// scramblers has a fixed point.
+func newOrderSheets(order []string, reflectorName string) (*[26]Sheet, error) {
+	setting, settingError := settings.NewBareSetting(order, reflectorName)
+	if settingError != nil {
+		return nil, settingError
+	}
+
+	sheets := new([26]Sheet)
+	for left := range sheets {
+		sheets[left] = Sheet{Order: order, Reflector: setting.Reflector.Name, Left: left}
+		for middle := range 26 {
+			for right := range 26 {
+				setting.Rotors.SetIndex(left*676 + middle*26 + right)
+
+				var steps [2 * Pairs]settings.Permutation
+				for index := range steps {
+					setting.Rotors[2].Position = (setting.Rotors[2].Position + 1) % 26
+					for in := range steps[index] {
+						steps[index][in] = uint8(setting.Scramble(in))
+					}
+				}
+
+				for pair := range Pairs {
+					for letter := range steps[pair] {
+						if int(steps[pair+Pairs][steps[pair][letter]]) == letter {
+							sheets[left].Females[middle][right] |= 1 << pair
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return sheets, nil
+}
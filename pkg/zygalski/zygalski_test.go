@@ -0,0 +1,143 @@
+package zygalski
+
+import (
+	"bytes"
+	"github.com/r3db34n1an/enigma/pkg/procedure"
+	"github.com/r3db34n1an/enigma/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"image/png"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+const testKey = "{rotors: [{name: III, position: A, ring_setting: H}, {name: I, position: A, ring_setting: Q}, {name: II, position: A, ring_setting: E}], reflector: B, plug_board: {B: X, X: B, C: L, L: C, D: R, R: D, G: O, O: G, H: K, K: H, J: Y, Y: J}}"
+
+func TestSheets(t *testing.T) {
+	var setting settings.Setting
+	assert.Nil(t, setting.Parse(testKey))
+
+	doubled, doubledError := procedure.NewDoubledIndicator(&setting)
+	assert.Nil(t, doubledError)
+
+	// every operator picks his own Grundstellung and sends it in the clear
+	random := rand.New(rand.NewPCG(3, 4))
+	messages := make([]*procedure.IndicatorMessage, 400)
+	for index := range messages {
+		grundstellung := procedure.RandomMessageKey(&setting, random)
+		indicator, indicatorError := doubled.Indicator(procedure.RandomMessageKey(&setting, random), grundstellung)
+		assert.Nil(t, indicatorError)
+		messages[index] = &procedure.IndicatorMessage{Grundstellung: grundstellung, Indicator: indicator}
+	}
+
+	sheets, sheetsError := NewSheets([]string{"I", "II", "III"}, "")
+	assert.Nil(t, sheetsError)
+	assert.Len(t, sheets.Orders, 6)
+	assert.Equal(t, []string{"I", "II", "III"}, sheets.Orders[0])
+
+	sheet, sheetError := sheets.Sheet([]string{"III", "I", "II"}, 4)
+	assert.Nil(t, sheetError)
+	assert.Equal(t, 4, sheet.Left)
+	for pair := range Pairs {
+		assert.InDelta(t, 0.4, sheet.Holes(pair), 0.15)
+	}
+
+	// about one message in nine repeats a letter in a pair
+	females, femalesError := FindFemales(messages)
+	assert.Nil(t, femalesError)
+	assert.Greater(t, len(females), 20)
+
+	// the rotor order and ring settings of the key let the light through all sheets
+	solutions, solveError := sheets.Solve(females, 0)
+	assert.Nil(t, solveError)
+	assert.NotEmpty(t, solutions)
+	assert.Equal(t, []string{"III", "I", "II"}, solutions[0].Order)
+	assert.Equal(t, "HQE", solutions[0].RingSettings)
+	assert.Equal(t, "B", solutions[0].Reflector)
+	assert.Greater(t, solutions[0].Matches, 15)
+	assert.Len(t, solutions, 1)
+
+	// the solution reads the message keys off the indicators with the plugs of the key
+	found, settingError := solutions[0].Setting(messages[0].Grundstellung)
+	assert.Nil(t, settingError)
+	found.PlugBoard = setting.Export().PlugBoard
+
+	var solved settings.Setting
+	assert.Nil(t, solved.Import(found))
+	doubled, doubledError = procedure.NewDoubledIndicator(&solved)
+	assert.Nil(t, doubledError)
+	_, keyError := doubled.MessageKey(messages[0].Indicator, messages[0].Grundstellung)
+	assert.Nil(t, keyError)
+
+	_, settingError = solutions[0].Setting("AB")
+	assert.NotNil(t, settingError)
+
+	// invalid input
+	_, sheetError = sheets.Sheet([]string{"I", "II", "IV"}, 0)
+	assert.NotNil(t, sheetError)
+
+	_, sheetError = sheets.Sheet([]string{"I", "II", "III"}, 26)
+	assert.NotNil(t, sheetError)
+
+	_, solveError = sheets.Solve(nil, 0)
+	assert.NotNil(t, solveError)
+
+	_, solveError = sheets.Solve([]Female{{Grundstellung: "AB1", Pair: 0}}, 0)
+	assert.NotNil(t, solveError)
+
+	_, solveError = sheets.Solve([]Female{{Grundstellung: "ABC", Pair: 3}}, 0)
+	assert.NotNil(t, solveError)
+
+	_, femalesError = FindFemales([]*procedure.IndicatorMessage{{Indicator: "ABCABC"}})
+	assert.NotNil(t, femalesError)
+
+	_, femalesError = FindFemales([]*procedure.IndicatorMessage{{Grundstellung: "ABC", Indicator: "ABCAB"}})
+	assert.NotNil(t, femalesError)
+
+	_, sheetsError = NewSheets([]string{"I", "II"}, "")
+	assert.NotNil(t, sheetsError)
+
+	_, sheetsError = NewSheets(nil, "X")
+	assert.NotNil(t, sheetsError)
+}
+
+func TestRender(t *testing.T) {
+	sheet := &Sheet{Order: []string{"I", "II", "III"}, Reflector: "B", Left: 2}
+	sheet.Females[0][1] = 1
+	sheet.Females[3][25] = 5
+
+	lines := strings.Split(strings.TrimSpace(sheet.String()), "\n")
+	assert.Len(t, lines, 28)
+	assert.Equal(t, "I-II-III B C", lines[0])
+	assert.Equal(t, "A ."+"1"+strings.Repeat(".", 24), lines[2])
+	assert.Equal(t, "D "+strings.Repeat(".", 25)+"5", lines[5])
+
+	var svg bytes.Buffer
+	assert.Nil(t, sheet.SVG(&svg, 0, 10))
+	assert.True(t, strings.HasPrefix(svg.String(), "<svg"))
+	assert.Contains(t, svg.String(), "<title>I-II-III B C 1-4</title>")
+
+	// the holes repeat, four times for the first row and column, twice for the last
+	assert.Equal(t, 4+2, strings.Count(svg.String(), `<rect x=`))
+
+	svg.Reset()
+	assert.Nil(t, sheet.SVG(&svg, 2, 10))
+	assert.Equal(t, 2, strings.Count(svg.String(), `<rect x=`))
+
+	var picture bytes.Buffer
+	assert.Nil(t, sheet.PNG(&picture, 0, 3))
+	decoded, decodeError := png.Decode(&picture)
+	assert.Nil(t, decodeError)
+	assert.Equal(t, 51*3, decoded.Bounds().Dx())
+
+	// a hole is dark, paper is light
+	red, _, _, _ := decoded.At(1*3+1, 0*3+1).RGBA()
+	assert.Equal(t, uint32(0), red)
+	red, _, _, _ = decoded.At(0, 0).RGBA()
+	assert.Greater(t, red, uint32(0))
+
+	assert.NotNil(t, sheet.SVG(&svg, 3, 10))
+	assert.NotNil(t, sheet.SVG(&svg, 0, 2))
+	assert.NotNil(t, sheet.PNG(&picture, -1, 3))
+	assert.NotNil(t, sheet.PNG(&picture, 0, 0))
+}